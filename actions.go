@@ -23,8 +23,47 @@ const (
 	// AccountLinkingAction means that the event concerns changes in account linking
 	// status.
 	AccountLinkingAction
+	// MessageReactionAction means that the event was a user reacting to a message
+	// or removing the reaction.
+	MessageReactionAction
+	// EchoAction means that the event was an echo of a message sent by the page.
+	EchoAction
+	// PassThreadControlAction means that thread control was passed to the app.
+	PassThreadControlAction
+	// TakeThreadControlAction means that thread control was taken from the app.
+	TakeThreadControlAction
+	// RequestThreadControlAction means that another app requested thread control.
+	RequestThreadControlAction
+	// AppRolesAction means that the page assigned app roles to the app.
+	AppRolesAction
 )
 
+var actionNames = map[Action]string{
+	UnknownAction:              "unknown",
+	TextAction:                 "text",
+	DeliveryAction:             "delivery",
+	ReadAction:                 "read",
+	PostBackAction:             "postback",
+	OptInAction:                "optin",
+	ReferralAction:             "referral",
+	AccountLinkingAction:       "account_linking",
+	MessageReactionAction:      "reaction",
+	EchoAction:                 "echo",
+	PassThreadControlAction:    "pass_thread_control",
+	TakeThreadControlAction:    "take_thread_control",
+	RequestThreadControlAction: "request_thread_control",
+	AppRolesAction:             "app_roles",
+}
+
+// String returns the name of the action.
+func (a Action) String() string {
+	if name, ok := actionNames[a]; ok {
+		return name
+	}
+
+	return actionNames[UnknownAction]
+}
+
 // SenderAction is used to send a specific action (event) to the Facebook.
 // The result of sending said action is supposed to give more interactivity to the bot.
 type SenderAction string
@@ -0,0 +1,116 @@
+package messenger
+
+// MessageBuilder composes a message sent with Response.SendMessage. The message is checked
+// against the documented limits of the Send API before it is sent.
+type MessageBuilder struct {
+	message SendMessage
+}
+
+// NewMessageBuilder returns new MessageBuilder of a RESPONSE message.
+func NewMessageBuilder() *MessageBuilder {
+	return &MessageBuilder{message: SendMessage{MessagingType: ResponseType}}
+}
+
+// Text sets the text of the message.
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	b.message.Message.Text = text
+	return b
+}
+
+// Attachment attaches an image, sound, video or a regular file by URL.
+func (b *MessageBuilder) Attachment(dataType AttachmentType, url string) *MessageBuilder {
+	return b.attach(NewAttachment(dataType, url))
+}
+
+// AttachmentID attaches an image, sound, video or a regular file uploaded before.
+func (b *MessageBuilder) AttachmentID(dataType AttachmentType, id string) *MessageBuilder {
+	return b.attach(&StructuredMessageAttachment{Type: dataType, Payload: StructuredMessagePayload{AttachmentID: id}})
+}
+
+// GenericTemplate makes the message a generic template of the elements.
+func (b *MessageBuilder) GenericTemplate(elements ...StructuredMessageElement) *MessageBuilder {
+	return b.attach(NewGenericTemplate(elements...))
+}
+
+// ButtonTemplate makes the message a button template.
+func (b *MessageBuilder) ButtonTemplate(text string, buttons ...StructuredMessageButton) *MessageBuilder {
+	return b.attach(NewButtonTemplate(text, buttons...))
+}
+
+// ListTemplate makes the message a compact list template of the elements.
+func (b *MessageBuilder) ListTemplate(elements ...StructuredMessageElement) *MessageBuilder {
+	return b.attach(NewListTemplate(elements...))
+}
+
+// Template makes the message a template with the typed payload, e.g. *Receipt or *Media.
+func (b *MessageBuilder) Template(t Template) *MessageBuilder {
+	return b.attach(NewTemplate(t))
+}
+
+// QuickReply adds the quick replies to the message.
+func (b *MessageBuilder) QuickReply(replies ...QuickReply) *MessageBuilder {
+	b.message.Message.QuickReplies = append(b.message.Message.QuickReplies, replies...)
+	return b
+}
+
+// MessagingType sets the messaging type of the message. It is RESPONSE by default.
+func (b *MessageBuilder) MessagingType(messagingType MessagingType) *MessageBuilder {
+	b.message.MessagingType = messagingType
+	return b
+}
+
+// Tag makes the message a MESSAGE_TAG one with the tag.
+func (b *MessageBuilder) Tag(tag string) *MessageBuilder {
+	b.message.MessagingType = MessageTagType
+	b.message.Tag = tag
+	return b
+}
+
+// Metadata sets the metadata passed to the other apps of the handover protocol.
+func (b *MessageBuilder) Metadata(metadata string) *MessageBuilder {
+	b.message.Message.Metadata = metadata
+	return b
+}
+
+// ThreadControl sets the thread control of the message.
+func (b *MessageBuilder) ThreadControl(control *ThreadControl) *MessageBuilder {
+	b.message.ThreadControl = control
+	return b
+}
+
+// Persona sends the message on behalf of the persona.
+func (b *MessageBuilder) Persona(personaID string) *MessageBuilder {
+	b.message.PersonaID = personaID
+	return b
+}
+
+// NotificationType sets the push notification type of the message.
+func (b *MessageBuilder) NotificationType(notificationType NotificationType) *MessageBuilder {
+	b.message.NotificationType = notificationType
+	return b
+}
+
+// Build returns the message without the recipient or a *ValidationError listing every violation.
+func (b *MessageBuilder) Build() (*SendMessage, error) {
+	m := b.message
+	m.Message.QuickReplies = append([]QuickReply(nil), m.Message.QuickReplies...)
+
+	if err := validateSendMessage(&m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (b *MessageBuilder) attach(attachment *StructuredMessageAttachment) *MessageBuilder {
+	b.message.Message.Attachment = attachment
+	return b
+}
+
+// SendMessage builds and sends the message. Nothing is sent when the message is invalid.
+func (r *Response) SendMessage(b *MessageBuilder) (QueryResponse, error) {
+	m := b.message
+	m.Recipient = r.to
+
+	return r.send(r.Context(), &m)
+}
@@ -0,0 +1,165 @@
+package messenger
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBuilder_Build(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewMessageBuilder().
+		Text("Pick a size").
+		QuickReply(
+			QuickReply{ContentType: "text", Title: "S", Payload: "SIZE:S"},
+			QuickReply{ContentType: "user_email"},
+		).
+		Tag("POST_PURCHASE_UPDATE").
+		Metadata("meta").
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, &SendMessage{
+		MessagingType: MessageTagType,
+		Tag:           "POST_PURCHASE_UPDATE",
+		Message: MessageData{
+			Text: "Pick a size",
+			QuickReplies: []QuickReply{
+				{ContentType: "text", Title: "S", Payload: "SIZE:S"},
+				{ContentType: "user_email"},
+			},
+			Metadata: "meta",
+		},
+	}, m)
+}
+
+func TestMessageBuilder_Build_Violations(t *testing.T) {
+	t.Parallel()
+
+	replies := make([]QuickReply, MaxQuickReplies+1)
+	for i := range replies {
+		replies[i] = QuickReply{ContentType: "text", Title: fmt.Sprint(i), Payload: "P"}
+	}
+	replies[2].Title = strings.Repeat("x", MaxQuickReplyTitleLength+1)
+
+	elements := make([]StructuredMessageElement, MaxGenericElements+1)
+	for i := range elements {
+		elements[i] = StructuredMessageElement{Title: "Item"}
+	}
+	elements[0].Buttons = &[]StructuredMessageButton{
+		{Type: "postback", Title: "A very long button title", Payload: "BUY"},
+		{Type: "web_url", Title: "Open"},
+	}
+
+	_, err := NewMessageBuilder().
+		MessagingType(MessageTagType).
+		GenericTemplate(elements...).
+		QuickReply(replies...).
+		Build()
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr), err)
+	assert.Equal(t, []Violation{
+		{Field: "tag", Message: "is required for the MESSAGE_TAG messaging type"},
+		{Field: "message.attachment.payload.elements", Message: "11 elements, at most 10 allowed"},
+		{Field: "message.attachment.payload.elements[0].buttons[0].title", Message: "24 characters, at most 20 allowed"},
+		{Field: "message.attachment.payload.elements[0].buttons[1].url", Message: "is required"},
+		{Field: "message.quick_replies", Message: "14 quick replies, at most 13 allowed"},
+		{Field: "message.quick_replies[2].title", Message: "21 characters, at most 20 allowed"},
+	}, verr.Violations)
+}
+
+func TestMessageBuilder_Build_Content(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		builder *MessageBuilder
+		field   string
+		message string
+	}{
+		"empty": {
+			builder: NewMessageBuilder(),
+			field:   "message",
+			message: "either text or attachment is required",
+		},
+		"text and attachment": {
+			builder: NewMessageBuilder().Text("hi").Attachment(ImageAttachment, "https://example.com/a.jpg"),
+			field:   "message",
+			message: "text and attachment are mutually exclusive",
+		},
+		"long text": {
+			builder: NewMessageBuilder().Text(strings.Repeat("я", MaxTextLength+1)),
+			field:   "message.text",
+			message: "2001 characters, at most 2000 allowed",
+		},
+		"attachment without url": {
+			builder: NewMessageBuilder().Attachment(ImageAttachment, ""),
+			field:   "message.attachment.payload",
+			message: "either url or attachment_id is required",
+		},
+		"button template without buttons": {
+			builder: NewMessageBuilder().ButtonTemplate("text"),
+			field:   "message.attachment.payload.buttons",
+			message: "at least 1 button is required",
+		},
+		"short list": {
+			builder: NewMessageBuilder().ListTemplate(StructuredMessageElement{Title: "One"}),
+			field:   "message.attachment.payload.elements",
+			message: "1 elements, at least 2 required",
+		},
+		"tag with response type": {
+			builder: NewMessageBuilder().Text("hi").Tag("ACCOUNT_UPDATE").MessagingType(ResponseType),
+			field:   "tag",
+			message: "is allowed only for the MESSAGE_TAG messaging type",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := test.builder.Build()
+
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr), err)
+			assert.Equal(t, []Violation{{Field: test.field, Message: test.message}}, verr.Violations)
+		})
+	}
+}
+
+func TestResponse_SendMessage(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"messaging_type": "RESPONSE",
+			"recipient": {"id": "154"},
+			"message": {"attachment": {"type": "image", "payload": {"url": "https://example.com/a.jpg"}}}
+		}`, string(body))
+		fmt.Fprint(w, `{"message_id": "ABCD"}`)
+	}))
+	defer srv.Close()
+
+	m := New(Options{HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	resp, err := m.Response(154).SendMessage(NewMessageBuilder().Attachment(ImageAttachment, "https://example.com/a.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD", resp.MessageID)
+
+	_, err = m.Response(154).SendMessage(NewMessageBuilder())
+	assert.EqualError(t, err, "invalid message: message: either text or attachment is required")
+	assert.Equal(t, 1, requests)
+}
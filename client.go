@@ -0,0 +1,181 @@
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGraphURL is the Graph API base URL used when Options.GraphURL is empty.
+const DefaultGraphURL = "https://graph.facebook.com"
+
+const (
+	profilePath          = "/v2.6/%v"
+	sendSettingsPath     = "/v2.6/me/thread_settings"
+	messengerProfilePath = "/v2.6/me/messenger_profile"
+	sendMessagePath      = "/%s/me/messages"
+	threadControlPath    = "/%s/me/pass_thread_control"
+	takeThreadPath       = "/%s/me/take_thread_control"
+	requestThreadPath    = "/%s/me/request_thread_control"
+	releaseThreadPath    = "/%s/me/release_thread_control"
+)
+
+// HTTPClient performs the HTTP requests to the Graph API. *http.Client satisfies it,
+// so a custom client can be used to add proxies, timeouts, mTLS and so on.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// graphClient builds and performs every outbound Graph API request.
+// The nil *graphClient is valid and uses http.DefaultClient and DefaultGraphURL.
+type graphClient struct {
+	httpClient HTTPClient
+	baseURL    string
+	retry      *RetryPolicy
+	// appSecret signs the access tokens with appsecret_proof unless the token source has its own secret.
+	appSecret string
+}
+
+func newGraphClient(httpClient HTTPClient, baseURL string, retry *RetryPolicy, appSecret string) *graphClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultGraphURL
+	}
+
+	return &graphClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		retry:      retry,
+		appSecret:  appSecret,
+	}
+}
+
+// url returns the absolute URL of the given Graph API path.
+func (g *graphClient) url(path string) string {
+	if g == nil {
+		return DefaultGraphURL + path
+	}
+
+	return g.baseURL + path
+}
+
+// newRequest creates a request to the Graph API path bound to the context.
+func (g *graphClient) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, g.url(path), body)
+}
+
+// do sends the request using the configured HTTP client and retry policy.
+func (g *graphClient) do(req *http.Request) (*http.Response, error) {
+	if g == nil {
+		return http.DefaultClient.Do(req)
+	}
+
+	if g.retry != nil && g.retry.MaxAttempts > 1 {
+		return g.retry.do(g.httpClient, req)
+	}
+
+	return g.httpClient.Do(req)
+}
+
+// send authorizes the request with the access token of the source and its appsecret_proof and sends it.
+// When the source replaces the rejected tokens the request is repeated once with the new token.
+// The nil source provides the empty token.
+func (g *graphClient) send(req *http.Request, tokens TokenSource) (*http.Response, error) {
+	if tokens == nil {
+		tokens = StaticTokenSource("")
+	}
+
+	token, err := tokens.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	query, secret := req.URL.RawQuery, g.proofSecret(tokens)
+	authorize(req, query, token, secret)
+
+	resp, err := g.do(req)
+
+	e, ok := tokens.(expirer)
+	if err != nil || !ok || resp.StatusCode < http.StatusBadRequest || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	// consume a *copy* of the response body
+	content, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewBuffer(content))
+
+	if qe := parseQueryError(content); qe == nil || qe.Code != InvalidTokenErrorCode {
+		return resp, nil
+	}
+
+	e.Expire(token)
+
+	refreshed, err := tokens.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	if refreshed == token {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	authorize(retry, query, refreshed, secret)
+
+	return g.do(retry)
+}
+
+// proofSecret returns the app secret signing the tokens of the source.
+func (g *graphClient) proofSecret(tokens TokenSource) string {
+	if s, ok := tokens.(secretSource); ok {
+		return s.proofSecret()
+	}
+
+	if g == nil {
+		return ""
+	}
+
+	return g.appSecret
+}
+
+// authorize sets the query of the request with the access token and its appsecret_proof added.
+// The proof is omitted when there is no app secret.
+func authorize(req *http.Request, query, token, secret string) {
+	if query != "" {
+		query += "&"
+	}
+
+	query += "access_token=" + url.QueryEscape(token)
+	if secret != "" {
+		query += "&appsecret_proof=" + appSecretProof(token, secret)
+	}
+
+	req.URL.RawQuery = query
+}
+
+// appSecretProof returns the hex encoded HMAC-SHA256 of the access token keyed with the app secret.
+func appSecretProof(token, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(token))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,69 @@
+package messenger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func proof(token, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGraphClient_AppSecretProof(t *testing.T) {
+	t.Parallel()
+
+	var queries []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		fmt.Fprint(w, `{"recipient_id": "154", "message_id": "mid", "data": []}`)
+	}))
+	defer srv.Close()
+
+	m := New(Options{
+		Token:     "default",
+		AppSecret: "secret",
+		Pages: TokenSources{
+			1: StaticTokenSource("first"),
+			2: &AppSecretTokenSource{TokenSource: StaticTokenSource("second"), AppSecret: "other"},
+			3: &AppSecretTokenSource{TokenSource: StaticTokenSource("third")},
+			4: AppSecretTokenSource{TokenSource: StaticTokenSource("fourth"), AppSecret: "other"},
+		},
+		HTTPClient: srv.Client(),
+		GraphURL:   srv.URL,
+	})
+
+	_, err := m.SendTo(context.Background(), Recipient{ID: 154}, MessageData{Text: "hello"})
+	require.NoError(t, err)
+
+	_, err = m.GetMessengerProfile(context.Background(), GreetingField)
+	require.NoError(t, err)
+
+	for _, pageID := range []int64{1, 2, 3, 4} {
+		page, err := m.Page(context.Background(), pageID)
+		require.NoError(t, err)
+		_, err = page.SendTo(context.Background(), Recipient{ID: 154}, MessageData{Text: "hello"})
+		require.NoError(t, err)
+	}
+
+	require.Len(t, queries, 6)
+	assert.Equal(t, proof("default", "secret"), queries[0].Get("appsecret_proof"))
+	assert.Equal(t, proof("default", "secret"), queries[1].Get("appsecret_proof"))
+	assert.Equal(t, "greeting", queries[1].Get("fields"))
+	assert.Equal(t, proof("first", "secret"), queries[2].Get("appsecret_proof"))
+	assert.Equal(t, proof("second", "other"), queries[3].Get("appsecret_proof"))
+	assert.Equal(t, "third", queries[4].Get("access_token"))
+	assert.NotContains(t, queries[4], "appsecret_proof")
+	assert.Equal(t, proof("fourth", "other"), queries[5].Get("appsecret_proof"))
+}
@@ -0,0 +1,74 @@
+// Command profilesync syncs the Messenger Profiles of the pages with the config files.
+//
+//	profilesync [-dry-run] [-page-token TOKEN] config.yaml...
+//
+// The page access token is taken from the environment variable named by the token_env key
+// of the config, the -page-token flag or the PAGE_ACCESS_TOKEN environment variable.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/retailcrm/messenger"
+	"github.com/retailcrm/messenger/profilesync"
+)
+
+var (
+	dryRun    = flag.Bool("dry-run", false, "Print the plan without changing the profiles")
+	pageToken = flag.String("page-token", os.Getenv("PAGE_ACCESS_TOKEN"), "The default page access token")
+	appSecret = flag.String("app-secret", os.Getenv("APP_SECRET"), "The app secret for the appsecret_proof")
+	graphURL  = flag.String("graph-url", "", "The Graph API URL")
+)
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Println("missing config files")
+		fmt.Println()
+		flag.Usage()
+
+		os.Exit(-1)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		if err := sync(path); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func sync(path string) error {
+	cfg, err := profilesync.Load(path)
+	if err != nil {
+		return err
+	}
+
+	token := *pageToken
+	if cfg.TokenEnv != "" {
+		token = os.Getenv(cfg.TokenEnv)
+	}
+	if token == "" {
+		return fmt.Errorf("missing page access token")
+	}
+
+	client := messenger.New(messenger.Options{Token: token, AppSecret: *appSecret, GraphURL: *graphURL})
+
+	plan, err := profilesync.Sync(context.Background(), client, cfg, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s:\n%s", path, plan)
+
+	return nil
+}
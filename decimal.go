@@ -0,0 +1,81 @@
+package messenger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number like a price or a quantity, e.g. "19.99". It is sent as a JSON number
+// without the rounding errors of the floating point numbers. The empty Decimal is omitted.
+type Decimal string
+
+var decimalRegexp = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// NewDecimal returns the Decimal of the unscaled value with the scale digits after the point,
+// e.g. NewDecimal(1999, 2) is "19.99". It is handy for the amounts stored in the minor currency units.
+func NewDecimal(unscaled int64, scale int) Decimal {
+	s := strconv.FormatInt(unscaled, 10)
+
+	var sign string
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	if scale <= 0 {
+		return Decimal(sign + s)
+	}
+
+	if len(s) <= scale {
+		s = strings.Repeat("0", scale-len(s)+1) + s
+	}
+
+	return Decimal(sign + s[:len(s)-scale] + "." + s[len(s)-scale:])
+}
+
+// ParseDecimal returns the Decimal of the string or an error if it is not a decimal number.
+func ParseDecimal(s string) (Decimal, error) {
+	d := Decimal(s)
+	if !d.Valid() {
+		return "", fmt.Errorf("invalid decimal %q", s)
+	}
+
+	return d, nil
+}
+
+// Valid reports whether the Decimal is a decimal number.
+func (d Decimal) Valid() bool {
+	return decimalRegexp.MatchString(string(d))
+}
+
+// String implements fmt.Stringer.
+func (d Decimal) String() string {
+	return string(d)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if !d.Valid() {
+		return nil, fmt.Errorf("invalid decimal %q", string(d))
+	}
+
+	return []byte(d), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Both the numbers and the strings are accepted,
+// null and the empty string leave the Decimal empty.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,130 @@
+package messenger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDedupTTL is how long the keys of the processed events are remembered when Options.DedupTTL is zero.
+const DefaultDedupTTL = 24 * time.Hour
+
+// DedupStore remembers the keys of the processed webhook events, so the events redelivered
+// by Facebook do not trigger the handlers again.
+type DedupStore interface {
+	// Seen marks the key as seen for the ttl and reports whether it has been seen before.
+	Seen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// MemoryDedupStore is a DedupStore keeping the keys in memory.
+type MemoryDedupStore struct {
+	mu        sync.Mutex
+	keys      map[string]time.Time
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryDedupStore returns new MemoryDedupStore.
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{
+		keys: map[string]time.Time{},
+		now:  time.Now,
+	}
+}
+
+// Seen implements DedupStore. The expired keys are swept once per ttl.
+func (s *MemoryDedupStore) Seen(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.After(s.nextSweep) {
+		for k, expires := range s.keys {
+			if now.After(expires) {
+				delete(s.keys, k)
+			}
+		}
+		s.nextSweep = now.Add(ttl)
+	}
+
+	expires, ok := s.keys[key]
+	if ok && !now.After(expires) {
+		return true, nil
+	}
+
+	s.keys[key] = now.Add(ttl)
+	return false, nil
+}
+
+// duplicate reports whether the event has been processed already. The store failures are logged
+// and the event is processed anyway.
+func (m *Messenger) duplicate(ctx context.Context, e Event) bool {
+	if m.dedupStore == nil {
+		return false
+	}
+
+	key := dedupKey(e)
+	if key == "" {
+		return false
+	}
+
+	seen, err := m.dedupStore.Seen(ctx, key, m.dedupTTL)
+	if err != nil {
+		m.log().Error("could not check webhook event duplicate",
+			"page_id", e.Info.PageID,
+			"sender_id", e.Info.Sender.ID,
+			"action", e.Action,
+			"error", err,
+		)
+		return false
+	}
+
+	if !seen {
+		return false
+	}
+
+	atomic.AddUint64(&m.duplicates, 1)
+	if m.onDuplicate != nil {
+		m.onDuplicate(e, key)
+	}
+
+	return true
+}
+
+// DuplicatesSuppressed returns the number of the redelivered webhook events which were not passed to the handlers.
+func (m *Messenger) DuplicatesSuppressed() uint64 {
+	return atomic.LoadUint64(&m.duplicates)
+}
+
+// dedupKey returns the key identifying the event: the message ID for the messages, the watermark for
+// the delivery and read receipts and the payload with the timestamp for the postbacks.
+func dedupKey(e Event) string {
+	info := e.Info
+
+	prefix := fmt.Sprintf("%d:%d:", info.PageID, info.Sender.ID)
+	if info.Standby {
+		prefix = "standby:" + prefix
+	}
+
+	switch e.Action {
+	case TextAction, EchoAction:
+		if info.Message.Mid == "" {
+			return ""
+		}
+		return prefix + "mid:" + info.Message.Mid
+	case DeliveryAction:
+		return fmt.Sprintf("%sdelivery:%d", prefix, info.Delivery.RawWatermark)
+	case ReadAction:
+		return fmt.Sprintf("%sread:%d", prefix, info.Read.RawWatermark)
+	case PostBackAction:
+		return fmt.Sprintf("%spostback:%d:%s", prefix, info.Timestamp, info.PostBack.Payload)
+	case MessageReactionAction:
+		return fmt.Sprintf("%sreaction:%d:%s:%s", prefix, info.Timestamp, info.Reaction.Action, info.Reaction.Mid)
+	case UnknownAction:
+		return ""
+	default:
+		return fmt.Sprintf("%s%s:%d", prefix, e.Action, info.Timestamp)
+	}
+}
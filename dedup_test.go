@@ -0,0 +1,67 @@
+package messenger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDedupStore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1543095111, 0)
+	s := NewMemoryDedupStore()
+	s.now = func() time.Time { return now }
+
+	seen, err := s.Seen(context.Background(), "key", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	seen, _ = s.Seen(context.Background(), "key", time.Minute)
+	assert.True(t, seen)
+
+	now = now.Add(2 * time.Minute)
+	seen, _ = s.Seen(context.Background(), "key", time.Minute)
+	assert.False(t, seen)
+}
+
+func TestMessenger_Dedup(t *testing.T) {
+	t.Parallel()
+
+	var duplicates []string
+	m := New(Options{
+		DedupStore: NewMemoryDedupStore(),
+		OnDuplicate: func(e Event, key string) {
+			duplicates = append(duplicates, key)
+		},
+	})
+
+	var messages, deliveries, postbacks int
+	m.HandleMessage(func(Message, *Response) { messages++ })
+	m.HandleDelivery(func(Delivery, *Response) { deliveries++ })
+	m.HandlePostBack(func(PostBack, *Response) { postbacks++ })
+
+	rec := Receive{Entry: []Entry{{ID: 222, Messaging: []MessageInfo{
+		{Sender: Sender{ID: 111}, Message: &Message{Mid: "m1"}},
+		{Sender: Sender{ID: 111}, Message: &Message{Mid: "m2"}},
+		{Sender: Sender{ID: 111}, Delivery: &Delivery{RawWatermark: 1543095111999}},
+		{Sender: Sender{ID: 111}, Timestamp: 1543095111999, PostBack: &PostBack{Payload: "MENU"}},
+	}}}}
+
+	m.dispatch(context.Background(), rec)
+	m.dispatch(context.Background(), rec)
+
+	assert.Equal(t, 2, messages)
+	assert.Equal(t, 1, deliveries)
+	assert.Equal(t, 1, postbacks)
+	assert.EqualValues(t, 4, m.DuplicatesSuppressed())
+	assert.Equal(t, []string{
+		"222:111:mid:m1",
+		"222:111:mid:m2",
+		"222:111:delivery:1543095111999",
+		"222:111:postback:1543095111999:MENU",
+	}, duplicates)
+}
@@ -0,0 +1,540 @@
+package messenger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndDialog is the state name which ends the dialog. The interrupted dialog is resumed then if there's any.
+const EndDialog = "$end"
+
+// DialogSession is the progress of a user in a dialog. It is kept in a StateStore.
+type DialogSession struct {
+	// Dialog is the name of the dialog.
+	Dialog string `json:"dialog"`
+	// State is the name of the current state.
+	State string `json:"state"`
+	// Data is the values collected in the dialog.
+	Data map[string]string `json:"data,omitempty"`
+	// UpdatedAt is when the current state was entered.
+	UpdatedAt time.Time `json:"updated_at"`
+	// Suspended are the interrupted dialogs. The last one is resumed first.
+	Suspended []DialogSession `json:"suspended,omitempty"`
+}
+
+// StateStore keeps the dialog sessions of the users.
+type StateStore interface {
+	// Load returns the session stored under the key or nil if there is none.
+	Load(ctx context.Context, key string) (*DialogSession, error)
+	// Save stores the session under the key.
+	Save(ctx context.Context, key string, s *DialogSession) error
+	// Delete removes the session stored under the key.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStateStore is a StateStore keeping the sessions in memory.
+type MemoryStateStore struct {
+	mu       sync.Mutex
+	sessions map[string]DialogSession
+}
+
+// NewMemoryStateStore returns new MemoryStateStore.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{sessions: map[string]DialogSession{}}
+}
+
+// Load implements StateStore.
+func (s *MemoryStateStore) Load(_ context.Context, key string) (*DialogSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[key]
+	if !ok {
+		return nil, nil
+	}
+
+	session = copyDialogSession(session)
+	return &session, nil
+}
+
+// Save implements StateStore.
+func (s *MemoryStateStore) Save(_ context.Context, key string, session *DialogSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[key] = copyDialogSession(*session)
+	return nil
+}
+
+// Delete implements StateStore.
+func (s *MemoryStateStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, key)
+	return nil
+}
+
+// copyDialogSession returns the deep copy of the session, so the stored one isn't shared with the callers.
+func copyDialogSession(session DialogSession) DialogSession {
+	session.Data = copyDialogData(session.Data)
+
+	if session.Suspended != nil {
+		suspended := make([]DialogSession, len(session.Suspended))
+		for i, s := range session.Suspended {
+			suspended[i] = copyDialogSession(s)
+		}
+		session.Suspended = suspended
+	}
+
+	return session
+}
+
+func copyDialogData(data map[string]string) map[string]string {
+	if data == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(data))
+	for k, v := range data {
+		c[k] = v
+	}
+
+	return c
+}
+
+// DialogContext is passed to the callbacks of the dialog states.
+type DialogContext struct {
+	ctx context.Context
+	// Session is the dialog session of the user.
+	Session *DialogSession
+	// Response is used for responding to the user.
+	Response *Response
+	// Event is the webhook event being processed.
+	Event Event
+	// Text is the text of the received message.
+	Text string
+	// Payload is the payload of the received quick reply or postback.
+	Payload string
+	// Params are the values of the payload pattern parameters of the transition.
+	Params map[string]string
+}
+
+// Context returns the context of the webhook event.
+func (c *DialogContext) Context() context.Context {
+	return c.ctx
+}
+
+// Get returns the value collected in the dialog.
+func (c *DialogContext) Get(key string) string {
+	return c.Session.Data[key]
+}
+
+// Set stores the value in the dialog session.
+func (c *DialogContext) Set(key, value string) {
+	if c.Session.Data == nil {
+		c.Session.Data = map[string]string{}
+	}
+
+	c.Session.Data[key] = value
+}
+
+// DialogState describes a state of a dialog.
+type DialogState struct {
+	// OnEnter is called when the dialog enters the state, e.g. to ask the user a question.
+	OnEnter func(c *DialogContext) error
+	// OnInput is called when the user sends a message, a quick reply or a postback not matching
+	// any transition of the state. It returns the name of the next state, empty to stay in the state.
+	// The events are passed to the regular handlers if it is nil.
+	OnInput func(c *DialogContext) (string, error)
+	// Timeout is how long the dialog waits for the user in the state. Zero waits forever.
+	Timeout time.Duration
+	// TimeoutState is the state entered when the user responds after the timeout.
+	// Leaving it blank ends the dialog, the late response is handled by the resumed dialog
+	// or by the next handlers then.
+	TimeoutState string
+}
+
+// dialogTransition moves the dialog to the state when the input matches.
+type dialogTransition struct {
+	text  string
+	route *payloadRoute
+	to    string
+}
+
+// Dialog is a multi-step conversation flow made of named states.
+type Dialog struct {
+	name        string
+	initial     string
+	states      map[string]DialogState
+	transitions map[string][]dialogTransition
+}
+
+// NewDialog returns new Dialog which starts in the initial state.
+func NewDialog(name, initial string) *Dialog {
+	return &Dialog{
+		name:        name,
+		initial:     initial,
+		states:      map[string]DialogState{},
+		transitions: map[string][]dialogTransition{},
+	}
+}
+
+// Name returns the name of the dialog.
+func (d *Dialog) Name() string {
+	return d.name
+}
+
+// State adds the state to the dialog.
+func (d *Dialog) State(name string, s DialogState) *Dialog {
+	d.states[name] = s
+	return d
+}
+
+// OnText moves the dialog from the state to another one when the user sends the text.
+// The text is compared case-insensitively.
+func (d *Dialog) OnText(from, text, to string) *Dialog {
+	d.transitions[from] = append(d.transitions[from], dialogTransition{text: text, to: to})
+	return d
+}
+
+// OnPayload moves the dialog from the state to another one when the user sends a quick reply
+// or a postback with the payload matching the pattern. The pattern syntax is the one of Messenger.HandlePayload.
+func (d *Dialog) OnPayload(from, pattern, to string) *Dialog {
+	route := compilePayloadRoute(pattern, nil)
+	d.transitions[from] = append(d.transitions[from], dialogTransition{route: &route, to: to})
+	return d
+}
+
+// match returns the transition of the state matching the input.
+func (d *Dialog) match(state string, c *DialogContext) (string, bool) {
+	for _, t := range d.transitions[state] {
+		if t.route == nil {
+			if c.Payload == "" && strings.EqualFold(strings.TrimSpace(c.Text), t.text) {
+				return t.to, true
+			}
+			continue
+		}
+
+		if c.Payload == "" {
+			continue
+		}
+
+		if params, ok := t.route.match(c.Payload); ok {
+			c.Params = params
+			return t.to, true
+		}
+	}
+
+	return "", false
+}
+
+// dialogTrigger starts the dialog from any state.
+type dialogTrigger struct {
+	text   string
+	route  *payloadRoute
+	dialog string
+}
+
+// Dialogs runs the dialogs of the users. The events received while a user is in a dialog
+// are consumed by the dialog and do not reach the regular handlers unless the current state
+// has no transition or OnInput for them.
+//
+// The events of a user are processed one at a time, so the dialog callbacks must not call Start
+// or End for the same user. The sessions kept in a shared StateStore are not locked across processes.
+type Dialogs struct {
+	store    StateStore
+	dialogs  map[string]*Dialog
+	triggers []dialogTrigger
+	now      func() time.Time
+
+	mu    sync.Mutex
+	locks map[string]*dialogLock
+}
+
+// dialogLock serializes the processing of the session stored under a key.
+type dialogLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewDialogs returns new Dialogs keeping the sessions in the store.
+func NewDialogs(store StateStore) *Dialogs {
+	return &Dialogs{
+		store:   store,
+		dialogs: map[string]*Dialog{},
+		now:     time.Now,
+		locks:   map[string]*dialogLock{},
+	}
+}
+
+// Add adds the dialog.
+func (ds *Dialogs) Add(d *Dialog) *Dialogs {
+	ds.dialogs[d.name] = d
+	return ds
+}
+
+// StartOnText starts the dialog when the user sends the text. The current dialog is interrupted
+// and resumed after the started one ends.
+func (ds *Dialogs) StartOnText(text, dialog string) *Dialogs {
+	ds.triggers = append(ds.triggers, dialogTrigger{text: text, dialog: dialog})
+	return ds
+}
+
+// StartOnPayload starts the dialog when the user sends a quick reply or a postback with the payload
+// matching the pattern. The current dialog is interrupted and resumed after the started one ends.
+func (ds *Dialogs) StartOnPayload(pattern, dialog string) *Dialogs {
+	route := compilePayloadRoute(pattern, nil)
+	ds.triggers = append(ds.triggers, dialogTrigger{route: &route, dialog: dialog})
+	return ds
+}
+
+// Start starts the dialog for the recipient of the Response. The current dialog is interrupted
+// and resumed after the started one ends.
+func (ds *Dialogs) Start(ctx context.Context, r *Response, pageID int64, dialog string) error {
+	key := dialogKey(pageID, r.to.ID)
+	defer ds.lock(key)()
+
+	session, err := ds.store.Load(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	c := &DialogContext{ctx: ctx, Response: r.WithContext(ctx), Session: session}
+	return ds.start(c, key, dialog)
+}
+
+// End ends all dialogs of the user.
+func (ds *Dialogs) End(ctx context.Context, pageID, userID int64) error {
+	key := dialogKey(pageID, userID)
+	defer ds.lock(key)()
+
+	return ds.store.Delete(ctx, key)
+}
+
+// lock locks the session stored under the key and returns the function unlocking it.
+func (ds *Dialogs) lock(key string) func() {
+	ds.mu.Lock()
+	l, ok := ds.locks[key]
+	if !ok {
+		l = &dialogLock{}
+		ds.locks[key] = l
+	}
+	l.refs++
+	ds.mu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+
+		ds.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(ds.locks, key)
+		}
+		ds.mu.Unlock()
+	}
+}
+
+// UseDialogs adds the middleware running the dialogs. Failures of the dialog callbacks are reported
+// like the ones of the handlers.
+func (m *Messenger) UseDialogs(ds *Dialogs) {
+	m.Use(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, e Event) error {
+			var consumed bool
+			err := m.invoke(e, func() error {
+				var err error
+				consumed, err = ds.handle(ctx, e)
+				return err
+			})
+
+			if consumed || err != nil {
+				return err
+			}
+
+			return next(ctx, e)
+		}
+	})
+}
+
+// handle processes the event and reports whether it was consumed by a dialog.
+func (ds *Dialogs) handle(ctx context.Context, e Event) (bool, error) {
+	c := &DialogContext{ctx: ctx, Response: e.Response.WithContext(ctx), Event: e}
+
+	switch {
+	case e.Info.Standby:
+		return false, nil
+	case e.Action == TextAction:
+		c.Text = e.Info.Message.Text
+		if e.Info.Message.QuickReply != nil {
+			c.Payload = e.Info.Message.QuickReply.Payload
+		}
+	case e.Action == PostBackAction:
+		c.Payload = e.Info.PostBack.Payload
+	default:
+		return false, nil
+	}
+
+	key := dialogKey(e.Info.PageID, e.Info.Sender.ID)
+	defer ds.lock(key)()
+
+	session, err := ds.store.Load(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	c.Session = session
+
+	for _, t := range ds.triggers {
+		if ds.triggered(t, c) {
+			return true, ds.start(c, key, t.dialog)
+		}
+	}
+
+	if c.Session == nil {
+		return false, nil
+	}
+
+	d, state, ok := ds.current(c.Session)
+	if !ok {
+		return false, ds.store.Delete(ctx, key)
+	}
+
+	if state.Timeout > 0 && ds.now().Sub(c.Session.UpdatedAt) > state.Timeout {
+		if state.TimeoutState != "" {
+			return true, ds.enter(c, key, state.TimeoutState)
+		}
+
+		// the late input goes to the resumed dialog or to the next handlers when there's none
+		resumed := len(c.Session.Suspended) > 0
+		if err := ds.enter(c, key, EndDialog); err != nil || !resumed {
+			return err != nil, err
+		}
+
+		if d, state, ok = ds.current(c.Session); !ok {
+			return false, ds.store.Delete(ctx, key)
+		}
+	}
+
+	if next, ok := d.match(c.Session.State, c); ok {
+		return true, ds.enter(c, key, next)
+	}
+
+	if state.OnInput == nil {
+		return false, nil
+	}
+
+	next, err := state.OnInput(c)
+	if err != nil {
+		return true, err
+	}
+
+	if next == "" {
+		return true, ds.store.Save(ctx, key, c.Session)
+	}
+
+	return true, ds.enter(c, key, next)
+}
+
+// triggered reports whether the input matches the trigger.
+func (ds *Dialogs) triggered(t dialogTrigger, c *DialogContext) bool {
+	if t.route == nil {
+		return c.Payload == "" && strings.EqualFold(strings.TrimSpace(c.Text), t.text)
+	}
+
+	if c.Payload == "" {
+		return false
+	}
+
+	params, ok := t.route.match(c.Payload)
+	if ok {
+		c.Params = params
+	}
+
+	return ok
+}
+
+// current returns the dialog and the state of the session.
+func (ds *Dialogs) current(s *DialogSession) (*Dialog, DialogState, bool) {
+	d, ok := ds.dialogs[s.Dialog]
+	if !ok {
+		return nil, DialogState{}, false
+	}
+
+	state, ok := d.states[s.State]
+	return d, state, ok
+}
+
+// start starts the dialog suspending the current one. The dialog which is current already
+// is restarted, the suspended sessions of the dialog are discarded.
+func (ds *Dialogs) start(c *DialogContext, key, dialog string) error {
+	d, ok := ds.dialogs[dialog]
+	if !ok {
+		return fmt.Errorf("unknown dialog %q", dialog)
+	}
+
+	session := &DialogSession{Dialog: d.name}
+	if c.Session != nil {
+		for _, s := range c.Session.Suspended {
+			if s.Dialog != d.name {
+				session.Suspended = append(session.Suspended, s)
+			}
+		}
+
+		if c.Session.Dialog != d.name {
+			current := *c.Session
+			current.Suspended = nil
+			session.Suspended = append(session.Suspended, current)
+		}
+	}
+	c.Session = session
+
+	return ds.enter(c, key, d.initial)
+}
+
+// enter moves the session to the state of the current dialog and calls its OnEnter.
+// Ending the dialog resumes the last suspended one by entering its state again.
+func (ds *Dialogs) enter(c *DialogContext, key, name string) error {
+	if name == EndDialog {
+		suspended := c.Session.Suspended
+		if len(suspended) == 0 {
+			return ds.store.Delete(c.ctx, key)
+		}
+
+		resumed := suspended[len(suspended)-1]
+		resumed.Suspended = suspended[:len(suspended)-1]
+		c.Session = &resumed
+		name = resumed.State
+	}
+
+	d, ok := ds.dialogs[c.Session.Dialog]
+	if !ok {
+		return fmt.Errorf("unknown dialog %q", c.Session.Dialog)
+	}
+
+	state, ok := d.states[name]
+	if !ok {
+		return fmt.Errorf("unknown state %q of dialog %q", name, d.name)
+	}
+
+	c.Session.State = name
+	c.Session.UpdatedAt = ds.now()
+
+	var err error
+	if state.OnEnter != nil {
+		err = state.OnEnter(c)
+	}
+
+	if saveErr := ds.store.Save(c.ctx, key, c.Session); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
+	return err
+}
+
+func dialogKey(pageID, userID int64) string {
+	return fmt.Sprintf("%d:%d", pageID, userID)
+}
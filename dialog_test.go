@@ -0,0 +1,354 @@
+package messenger
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dialogEvent(info MessageInfo) Receive {
+	info.Sender = Sender{ID: 1}
+	info.Recipient = Recipient{ID: 2}
+	return Receive{Entry: []Entry{{ID: 2, Messaging: []MessageInfo{info}}}}
+}
+
+func dialogText(text string) Receive {
+	return dialogEvent(MessageInfo{Message: &Message{Text: text}})
+}
+
+func dialogPostBack(payload string) Receive {
+	return dialogEvent(MessageInfo{PostBack: &PostBack{Payload: payload}})
+}
+
+func TestDialogs(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	store := NewMemoryStateStore()
+
+	order := NewDialog("order", "size").
+		State("size", DialogState{
+			OnEnter: func(c *DialogContext) error {
+				calls = append(calls, "ask size")
+				return nil
+			},
+		}).
+		OnPayload("size", "SIZE:{size}", "address").
+		State("address", DialogState{
+			OnEnter: func(c *DialogContext) error {
+				if size := c.Params["size"]; size != "" {
+					c.Set("size", size)
+				}
+				calls = append(calls, "ask address")
+				return nil
+			},
+			OnInput: func(c *DialogContext) (string, error) {
+				c.Set("address", c.Text)
+				return "confirm", nil
+			},
+		}).
+		State("confirm", DialogState{
+			OnEnter: func(c *DialogContext) error {
+				calls = append(calls, "confirm "+c.Get("size")+" to "+c.Get("address"))
+				return nil
+			},
+		}).
+		OnText("confirm", "yes", EndDialog)
+
+	support := NewDialog("support", "ask").
+		State("ask", DialogState{
+			OnEnter: func(c *DialogContext) error {
+				calls = append(calls, "how can I help")
+				return nil
+			},
+		}).
+		OnText("ask", "bye", EndDialog)
+
+	ds := NewDialogs(store).Add(order).Add(support).
+		StartOnPayload("ORDER", "order").
+		StartOnText("help", "support")
+
+	m := &Messenger{}
+	m.UseDialogs(ds)
+	m.HandleMessage(func(msg Message, r *Response) {
+		calls = append(calls, "handler "+msg.Text)
+	})
+	m.HandlePostBack(func(p PostBack, r *Response) {
+		calls = append(calls, "handler "+p.Payload)
+	})
+
+	for _, r := range []Receive{
+		dialogText("hello"),
+		dialogPostBack("ORDER"),
+		dialogText("not a size"),
+		dialogPostBack("SIZE:XL"),
+		dialogText("Baker st."),
+		dialogText("Help"),
+		dialogText("bye"),
+		dialogText("yes"),
+		dialogText("hello"),
+	} {
+		m.dispatch(context.Background(), r)
+	}
+
+	assert.Equal(t, []string{
+		"handler hello",
+		"ask size",
+		"handler not a size",
+		"ask address",
+		"confirm XL to Baker st.",
+		"how can I help",
+		"confirm XL to Baker st.",
+		"handler hello",
+	}, calls)
+
+	session, err := store.Load(context.Background(), "2:1")
+	require.NoError(t, err)
+	assert.Nil(t, session)
+}
+
+func TestDialogs_Timeout(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1600000000, 0)
+
+	var calls []string
+	d := NewDialog("quiz", "question").
+		State("question", DialogState{
+			Timeout:      time.Minute,
+			TimeoutState: "late",
+			OnInput: func(c *DialogContext) (string, error) {
+				calls = append(calls, "answer "+c.Text)
+				return "", nil
+			},
+		}).
+		State("late", DialogState{
+			OnEnter: func(c *DialogContext) error {
+				calls = append(calls, "too late")
+				return nil
+			},
+		}).
+		OnText("late", "again", "question")
+
+	ds := NewDialogs(NewMemoryStateStore()).Add(d)
+	ds.now = func() time.Time { return now }
+
+	m := &Messenger{}
+	m.UseDialogs(ds)
+	m.HandleMessage(func(msg Message, r *Response) {
+		calls = append(calls, "handler "+msg.Text)
+	})
+
+	require.NoError(t, ds.Start(context.Background(), m.Response(1), 2, "quiz"))
+
+	m.dispatch(context.Background(), dialogText("42"))
+	now = now.Add(2 * time.Minute)
+	m.dispatch(context.Background(), dialogText("43"))
+	m.dispatch(context.Background(), dialogText("44"))
+	m.dispatch(context.Background(), dialogText("again"))
+	m.dispatch(context.Background(), dialogText("45"))
+
+	assert.Equal(t, []string{"answer 42", "too late", "handler 44", "answer 45"}, calls)
+}
+
+func TestDialogs_TimeoutEnd(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1600000000, 0)
+
+	var calls []string
+	quiz := NewDialog("quiz", "question").
+		State("question", DialogState{
+			Timeout: time.Minute,
+			OnInput: func(c *DialogContext) (string, error) {
+				calls = append(calls, "answer "+c.Text)
+				return "", nil
+			},
+		})
+	menu := NewDialog("menu", "choose").
+		State("choose", DialogState{
+			OnEnter: func(c *DialogContext) error {
+				calls = append(calls, "choose")
+				return nil
+			},
+		}).
+		OnText("choose", "quiz", EndDialog)
+
+	ds := NewDialogs(NewMemoryStateStore()).Add(quiz).Add(menu).StartOnText("menu", "menu")
+	ds.now = func() time.Time { return now }
+
+	m := &Messenger{}
+	m.UseDialogs(ds)
+	m.HandleMessage(func(msg Message, r *Response) {
+		calls = append(calls, "handler "+msg.Text)
+	})
+
+	// the late input of the ended dialog goes to the handlers
+	require.NoError(t, ds.Start(context.Background(), m.Response(1), 2, "quiz"))
+	now = now.Add(2 * time.Minute)
+	m.dispatch(context.Background(), dialogText("42"))
+
+	// and to the resumed dialog when there's one
+	m.dispatch(context.Background(), dialogText("menu"))
+	require.NoError(t, ds.Start(context.Background(), m.Response(1), 2, "quiz"))
+	now = now.Add(2 * time.Minute)
+	m.dispatch(context.Background(), dialogText("quiz"))
+
+	assert.Equal(t, []string{"handler 42", "choose", "choose"}, calls)
+
+	session, err := ds.store.Load(context.Background(), "2:1")
+	require.NoError(t, err)
+	assert.Nil(t, session)
+}
+
+func TestDialogs_Retrigger(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	order := NewDialog("order", "size").
+		State("size", DialogState{
+			OnEnter: func(c *DialogContext) error {
+				calls = append(calls, "ask size")
+				return nil
+			},
+		}).
+		OnText("size", "done", EndDialog)
+	support := NewDialog("support", "ask").
+		State("ask", DialogState{}).
+		OnText("ask", "bye", EndDialog)
+
+	store := NewMemoryStateStore()
+	ds := NewDialogs(store).Add(order).Add(support).
+		StartOnText("order", "order").
+		StartOnText("help", "support")
+
+	m := &Messenger{}
+	m.UseDialogs(ds)
+
+	for i := 0; i < 5; i++ {
+		m.dispatch(context.Background(), dialogText("order"))
+	}
+
+	session, err := store.Load(context.Background(), "2:1")
+	require.NoError(t, err)
+	assert.Equal(t, "order", session.Dialog)
+	assert.Empty(t, session.Suspended)
+
+	// order -> support -> order leaves a single order session
+	m.dispatch(context.Background(), dialogText("help"))
+	m.dispatch(context.Background(), dialogText("order"))
+
+	session, err = store.Load(context.Background(), "2:1")
+	require.NoError(t, err)
+	assert.Equal(t, "order", session.Dialog)
+	require.Len(t, session.Suspended, 1)
+	assert.Equal(t, "support", session.Suspended[0].Dialog)
+
+	m.dispatch(context.Background(), dialogText("done"))
+	m.dispatch(context.Background(), dialogText("bye"))
+
+	session, err = store.Load(context.Background(), "2:1")
+	require.NoError(t, err)
+	assert.Nil(t, session)
+	assert.Len(t, calls, 6)
+}
+
+func TestDialogs_Errors(t *testing.T) {
+	t.Parallel()
+
+	var reported []error
+
+	ds := NewDialogs(NewMemoryStateStore()).
+		Add(NewDialog("broken", "missing")).
+		StartOnText("start", "broken").
+		StartOnText("unknown", "nope")
+
+	m := &Messenger{onError: func(e Event, err error, recovered interface{}) {
+		reported = append(reported, err)
+	}}
+	m.UseDialogs(ds)
+	m.HandleMessage(func(msg Message, r *Response) {
+		t.Error("the failed events must not reach the handlers")
+	})
+
+	m.dispatch(context.Background(), dialogText("start"))
+	m.dispatch(context.Background(), dialogText("unknown"))
+
+	require.Len(t, reported, 2)
+	assert.EqualError(t, reported[0], `unknown state "missing" of dialog "broken"`)
+	assert.EqualError(t, reported[1], `unknown dialog "nope"`)
+}
+
+func TestMemoryStateStore_Copy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+
+	session := &DialogSession{
+		Dialog:    "quiz",
+		Data:      map[string]string{"answer": "a"},
+		Suspended: []DialogSession{{Dialog: "menu", Data: map[string]string{"page": "1"}}},
+	}
+	require.NoError(t, store.Save(ctx, "key", session))
+
+	session.Data["answer"] = "b"
+	session.Suspended[0].Data["page"] = "2"
+	session.Suspended[0].State = "changed"
+
+	loaded, err := store.Load(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "a", loaded.Data["answer"])
+	assert.Equal(t, "1", loaded.Suspended[0].Data["page"])
+	assert.Empty(t, loaded.Suspended[0].State)
+
+	loaded.Data["answer"] = "c"
+	loaded.Suspended[0].Data["page"] = "3"
+
+	loaded, err = store.Load(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "a", loaded.Data["answer"])
+	assert.Equal(t, "1", loaded.Suspended[0].Data["page"])
+}
+
+func TestDialogs_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	d := NewDialog("counter", "counting").
+		State("counting", DialogState{
+			OnInput: func(c *DialogContext) (string, error) {
+				n, _ := strconv.Atoi(c.Get("count"))
+				time.Sleep(time.Millisecond)
+				c.Set("count", strconv.Itoa(n+1))
+				return "", nil
+			},
+		})
+
+	store := NewMemoryStateStore()
+	ds := NewDialogs(store).Add(d)
+
+	m := &Messenger{}
+	m.UseDialogs(ds)
+
+	require.NoError(t, ds.Start(context.Background(), m.Response(1), 2, "counter"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.dispatch(context.Background(), dialogText("+1"))
+		}()
+	}
+	wg.Wait()
+
+	session, err := store.Load(context.Background(), dialogKey(2, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "50", session.Data["count"])
+	assert.Empty(t, ds.locks)
+}
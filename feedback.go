@@ -0,0 +1,209 @@
+package messenger
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// The documented limits of the customer feedback template.
+const (
+	// MaxFeedbackTitleLength is the maximal length of the title of a customer feedback template.
+	MaxFeedbackTitleLength = 65
+	// MaxFeedbackSubtitleLength is the maximal length of the subtitle of a customer feedback template.
+	MaxFeedbackSubtitleLength = 80
+	// MaxFeedbackPlaceholderLength is the maximal length of the placeholder of a free form follow-up.
+	MaxFeedbackPlaceholderLength = 65
+	// MaxFeedbackQuestionIDLength is the maximal length of the ID of a question.
+	MaxFeedbackQuestionIDLength = 80
+	// MaxFeedbackExpiresInDays is the maximal number of days the feedback may be given within.
+	MaxFeedbackExpiresInDays = 7
+)
+
+// FeedbackQuestionType is the type of a customer feedback question.
+type FeedbackQuestionType string
+
+const (
+	// CSATQuestion is a customer satisfaction question.
+	CSATQuestion FeedbackQuestionType = "csat"
+	// NPSQuestion is a net promoter score question.
+	NPSQuestion FeedbackQuestionType = "nps"
+	// CESQuestion is a customer effort score question.
+	CESQuestion FeedbackQuestionType = "ces"
+)
+
+// FeedbackScoreLabel is the order of the labels of a score.
+type FeedbackScoreLabel string
+
+const (
+	// NegPosScoreLabel is the negative to positive labels.
+	NegPosScoreLabel FeedbackScoreLabel = "neg_pos"
+	// PosNegScoreLabel is the positive to negative labels.
+	PosNegScoreLabel FeedbackScoreLabel = "pos_neg"
+)
+
+// FeedbackScoreOption is the scale of a score.
+type FeedbackScoreOption string
+
+const (
+	// OneToFiveScore is a 1 to 5 scale of the csat and ces questions.
+	OneToFiveScore FeedbackScoreOption = "one_to_five"
+	// FiveStarsScore is a five stars scale of the csat questions.
+	FiveStarsScore FeedbackScoreOption = "five_stars"
+	// FiveEmojisScore is a five emojis scale of the csat questions.
+	FiveEmojisScore FeedbackScoreOption = "five_emojis"
+	// OneToSevenScore is a 1 to 7 scale of the ces questions.
+	OneToSevenScore FeedbackScoreOption = "one_to_seven"
+	// ZeroToTenScore is a 0 to 10 scale of the nps questions.
+	ZeroToTenScore FeedbackScoreOption = "zero_to_ten"
+)
+
+// feedbackScoreOptions are the scales allowed for the question types.
+var feedbackScoreOptions = map[FeedbackQuestionType][]FeedbackScoreOption{
+	CSATQuestion: {OneToFiveScore, FiveStarsScore, FiveEmojisScore},
+	NPSQuestion:  {ZeroToTenScore},
+	CESQuestion:  {OneToFiveScore, OneToSevenScore},
+}
+
+var feedbackQuestionIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// CustomerFeedback is the payload of a customer feedback template asking the user to rate the business.
+type CustomerFeedback struct {
+	// Title is the title of the template. Required.
+	Title string `json:"title"`
+	// Subtitle is the subtitle of the template.
+	Subtitle string `json:"subtitle,omitempty"`
+	// ButtonTitle is the title of the button opening the feedback screen. Required.
+	ButtonTitle string `json:"button_title"`
+	// Question is the question of the feedback screen.
+	Question FeedbackQuestion `json:"-"`
+	// PrivacyURL is the URL of the privacy policy of the business. Required.
+	PrivacyURL string `json:"-"`
+	// ExpiresInDays is how many days the feedback may be given within, 1 by default.
+	ExpiresInDays int `json:"expires_in_days,omitempty"`
+}
+
+// FeedbackQuestion is a question of a customer feedback template.
+type FeedbackQuestion struct {
+	// ID identifies the answers in the messaging_feedback webhook event. Required.
+	ID string `json:"id"`
+	// Type is the type of the question. Required.
+	Type FeedbackQuestionType `json:"type"`
+	// Title is the question itself. The default one is used when empty.
+	Title string `json:"title,omitempty"`
+	// ScoreLabel is the order of the labels, neg_pos by default.
+	ScoreLabel FeedbackScoreLabel `json:"score_label,omitempty"`
+	// ScoreOption is the scale of the score. Required.
+	ScoreOption FeedbackScoreOption `json:"score_option"`
+	// FollowUpPlaceholder enables the free form follow-up with the placeholder.
+	FollowUpPlaceholder string `json:"-"`
+}
+
+// TemplateType implements Template.
+func (f *CustomerFeedback) TemplateType() string {
+	return "customer_feedback"
+}
+
+// MarshalJSON implements json.Marshaler.
+func (f *CustomerFeedback) MarshalJSON() ([]byte, error) {
+	type followUp struct {
+		Type        string `json:"type"`
+		Placeholder string `json:"placeholder"`
+	}
+	type question struct {
+		FeedbackQuestion
+		FollowUp *followUp `json:"follow_up,omitempty"`
+	}
+	type screen struct {
+		Questions []question `json:"questions"`
+	}
+	type privacy struct {
+		URL string `json:"url"`
+	}
+	type feedback CustomerFeedback
+
+	q := question{FeedbackQuestion: f.Question}
+	if f.Question.FollowUpPlaceholder != "" {
+		q.FollowUp = &followUp{Type: "free_form", Placeholder: f.Question.FollowUpPlaceholder}
+	}
+
+	return json.Marshal(struct {
+		TemplateType string `json:"template_type"`
+		*feedback
+		FeedbackScreens []screen `json:"feedback_screens"`
+		BusinessPrivacy privacy  `json:"business_privacy"`
+	}{
+		TemplateType:    f.TemplateType(),
+		feedback:        (*feedback)(f),
+		FeedbackScreens: []screen{{Questions: []question{q}}},
+		BusinessPrivacy: privacy{URL: f.PrivacyURL},
+	})
+}
+
+func (f *CustomerFeedback) validate(v *validator, field string) {
+	v.required(field+".title", f.Title)
+	v.maxLength(field+".title", f.Title, MaxFeedbackTitleLength)
+	v.maxLength(field+".subtitle", f.Subtitle, MaxFeedbackSubtitleLength)
+	v.required(field+".button_title", f.ButtonTitle)
+	v.maxLength(field+".button_title", f.ButtonTitle, MaxButtonTitleLength)
+	v.required(field+".business_privacy.url", f.PrivacyURL)
+
+	if f.ExpiresInDays < 0 || f.ExpiresInDays > MaxFeedbackExpiresInDays {
+		v.add(field+".expires_in_days", "must be between 1 and %d", MaxFeedbackExpiresInDays)
+	}
+
+	q := f.Question
+	qf := field + ".feedback_screens[0].questions[0]"
+
+	switch {
+	case q.ID == "":
+		v.add(qf+".id", "is required")
+	case !feedbackQuestionIDRegexp.MatchString(q.ID):
+		v.add(qf+".id", "%q must contain only letters, digits and underscores", q.ID)
+	}
+	v.maxLength(qf+".id", q.ID, MaxFeedbackQuestionIDLength)
+
+	options, ok := feedbackScoreOptions[q.Type]
+	switch {
+	case q.Type == "":
+		v.add(qf+".type", "is required")
+	case !ok:
+		v.add(qf+".type", "%q is not csat, nps or ces", q.Type)
+	case q.ScoreOption == "":
+		v.add(qf+".score_option", "is required")
+	case !containsScoreOption(options, q.ScoreOption):
+		v.add(qf+".score_option", "%q is not allowed for the %s questions", q.ScoreOption, q.Type)
+	}
+
+	switch q.ScoreLabel {
+	case "", NegPosScoreLabel, PosNegScoreLabel:
+	default:
+		v.add(qf+".score_label", "%q is not neg_pos or pos_neg", q.ScoreLabel)
+	}
+
+	v.maxLength(qf+".follow_up.placeholder", q.FollowUpPlaceholder, MaxFeedbackPlaceholderLength)
+}
+
+func containsScoreOption(options []FeedbackScoreOption, option FeedbackScoreOption) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CustomerFeedbackTemplate sends the customer feedback template.
+// Nothing is sent when the feedback is invalid, the *ValidationError lists every violation then.
+func (r *Response) CustomerFeedbackTemplate(feedback CustomerFeedback, opts ...SendOption) (QueryResponse, error) {
+	return r.Send(r.Context(), MessageData{Attachment: NewTemplate(&feedback)}, opts...)
+}
+
+// CustomerFeedbackTemplate sends the customer feedback template to the recipient.
+func (m *Messenger) CustomerFeedbackTemplate(
+	to Recipient,
+	feedback CustomerFeedback,
+	opts ...SendOption,
+) (QueryResponse, error) {
+	return m.newResponse(to).CustomerFeedbackTemplate(feedback, opts...)
+}
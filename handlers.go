@@ -0,0 +1,118 @@
+package messenger
+
+// MessageHandlerE is like MessageHandler but returns an error which is reported to Options.OnError.
+type MessageHandlerE func(Message, *Response) error
+
+// HandleMessageE adds a new MessageHandlerE to the Messenger which will be triggered by the messages.
+func (m *Messenger) HandleMessageE(f MessageHandlerE) {
+	m.messageHandlers = append(m.messageHandlers, f)
+}
+
+// EchoHandlerE is like EchoHandler but returns an error which is reported to Options.OnError.
+type EchoHandlerE func(Message, *Response) error
+
+// HandleEchoE adds a new EchoHandlerE to the Messenger which will be triggered by the message echoes.
+func (m *Messenger) HandleEchoE(f EchoHandlerE) {
+	m.echoHandlers = append(m.echoHandlers, f)
+}
+
+// DeliveryHandlerE is like DeliveryHandler but returns an error which is reported to Options.OnError.
+type DeliveryHandlerE func(Delivery, *Response) error
+
+// HandleDeliveryE adds a new DeliveryHandlerE to the Messenger which will be triggered by the delivery receipts.
+func (m *Messenger) HandleDeliveryE(f DeliveryHandlerE) {
+	m.deliveryHandlers = append(m.deliveryHandlers, f)
+}
+
+// OptInHandlerE is like OptInHandler but returns an error which is reported to Options.OnError.
+type OptInHandlerE func(OptIn, *Response) error
+
+// HandleOptInE adds a new OptInHandlerE to the Messenger which will be triggered by the opt-ins.
+func (m *Messenger) HandleOptInE(f OptInHandlerE) {
+	m.optInHandlers = append(m.optInHandlers, f)
+}
+
+// ReadHandlerE is like ReadHandler but returns an error which is reported to Options.OnError.
+type ReadHandlerE func(Read, *Response) error
+
+// HandleReadE adds a new ReadHandlerE to the Messenger which will be triggered by the read receipts.
+func (m *Messenger) HandleReadE(f ReadHandlerE) {
+	m.readHandlers = append(m.readHandlers, f)
+}
+
+// PostBackHandlerE is like PostBackHandler but returns an error which is reported to Options.OnError.
+type PostBackHandlerE func(PostBack, *Response) error
+
+// HandlePostBackE adds a new PostBackHandlerE to the Messenger which will be triggered by the postbacks.
+func (m *Messenger) HandlePostBackE(f PostBackHandlerE) {
+	m.postBackHandlers = append(m.postBackHandlers, f)
+}
+
+// ReferralHandlerE is like ReferralHandler but returns an error which is reported to Options.OnError.
+type ReferralHandlerE func(ReferralMessage, *Response) error
+
+// HandleReferralE adds a new ReferralHandlerE to the Messenger which will be triggered by the referrals.
+func (m *Messenger) HandleReferralE(f ReferralHandlerE) {
+	m.referralHandlers = append(m.referralHandlers, f)
+}
+
+// AccountLinkingHandlerE is like AccountLinkingHandler but returns an error which is reported to Options.OnError.
+type AccountLinkingHandlerE func(AccountLinking, *Response) error
+
+// HandleAccountLinkingE adds a new AccountLinkingHandlerE to the Messenger which will be triggered
+// by the account linking events.
+func (m *Messenger) HandleAccountLinkingE(f AccountLinkingHandlerE) {
+	m.accountLinkingHandlers = append(m.accountLinkingHandlers, f)
+}
+
+// ReactionHandlerE is like ReactionHandler but returns an error which is reported to Options.OnError.
+type ReactionHandlerE func(IGMessageReaction, *Response) error
+
+// HandleReactionE adds a new ReactionHandlerE to the Messenger which will be triggered by the reactions.
+func (m *Messenger) HandleReactionE(f ReactionHandlerE) {
+	m.reactionHandlers = append(m.reactionHandlers, f)
+}
+
+// PassThreadControlHandlerE is like PassThreadControlHandler but returns an error which is reported to Options.OnError.
+type PassThreadControlHandlerE func(PassThreadControlEvent, *Response) error
+
+// HandlePassThreadControlE adds a new PassThreadControlHandlerE to the Messenger which will be triggered
+// by the pass thread control events.
+func (m *Messenger) HandlePassThreadControlE(f PassThreadControlHandlerE) {
+	m.passThreadHandlers = append(m.passThreadHandlers, f)
+}
+
+// TakeThreadControlHandlerE is like TakeThreadControlHandler but returns an error which is reported to Options.OnError.
+type TakeThreadControlHandlerE func(TakeThreadControlEvent, *Response) error
+
+// HandleTakeThreadControlE adds a new TakeThreadControlHandlerE to the Messenger which will be triggered
+// by the take thread control events.
+func (m *Messenger) HandleTakeThreadControlE(f TakeThreadControlHandlerE) {
+	m.takeThreadHandlers = append(m.takeThreadHandlers, f)
+}
+
+// RequestThreadControlHandlerE is like RequestThreadControlHandler but returns an error which is reported
+// to Options.OnError.
+type RequestThreadControlHandlerE func(RequestThreadControlEvent, *Response) error
+
+// HandleRequestThreadControlE adds a new RequestThreadControlHandlerE to the Messenger which will be triggered
+// by the request thread control events.
+func (m *Messenger) HandleRequestThreadControlE(f RequestThreadControlHandlerE) {
+	m.requestThreadHandlers = append(m.requestThreadHandlers, f)
+}
+
+// AppRolesHandlerE is like AppRolesHandler but returns an error which is reported to Options.OnError.
+type AppRolesHandlerE func(AppRolesEvent, *Response) error
+
+// HandleAppRolesE adds a new AppRolesHandlerE to the Messenger which will be triggered by the app roles events.
+func (m *Messenger) HandleAppRolesE(f AppRolesHandlerE) {
+	m.appRolesHandlers = append(m.appRolesHandlers, f)
+}
+
+// StandbyHandlerE is like StandbyHandler but returns an error which is reported to Options.OnError.
+type StandbyHandlerE func(MessageInfo, *Response) error
+
+// HandleStandbyE adds a new StandbyHandlerE to the Messenger which will be triggered by the standby events.
+func (m *Messenger) HandleStandbyE(f StandbyHandlerE) {
+	m.standbyHandlers = append(m.standbyHandlers, f)
+}
@@ -0,0 +1,98 @@
+package messenger
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// AppID is the ID of an app taking part in the handover protocol.
+// Facebook sends it either as a string or as a number.
+type AppID int64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (id *AppID) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+
+	v, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*id = AppID(v)
+	return nil
+}
+
+// PassThreadControlEvent represents the event fired when thread control is passed to the app.
+type PassThreadControlEvent struct {
+	// Sender is the user the thread belongs to.
+	Sender Sender `json:"-"`
+	// Recipient is the page the thread belongs to.
+	Recipient Recipient `json:"-"`
+	// Time is when the thread control was passed.
+	Time time.Time `json:"-"`
+	// Platform is the platform the thread control was passed on.
+	Platform Platform `json:"-"`
+	// NewOwnerAppID is the ID of the app the thread control was passed to.
+	NewOwnerAppID AppID `json:"new_owner_app_id"`
+	// PreviousOwnerAppID is the ID of the app the thread control was passed from.
+	PreviousOwnerAppID AppID `json:"previous_owner_app_id"`
+	// Metadata is the custom string sent by the previous owner.
+	Metadata string `json:"metadata"`
+}
+
+// TakeThreadControlEvent represents the event fired when thread control is taken from the app.
+type TakeThreadControlEvent struct {
+	// Sender is the user the thread belongs to.
+	Sender Sender `json:"-"`
+	// Recipient is the page the thread belongs to.
+	Recipient Recipient `json:"-"`
+	// Time is when the thread control was taken.
+	Time time.Time `json:"-"`
+	// Platform is the platform the thread control was taken on.
+	Platform Platform `json:"-"`
+	// PreviousOwnerAppID is the ID of the app the thread control was taken from.
+	PreviousOwnerAppID AppID `json:"previous_owner_app_id"`
+	// NewOwnerAppID is the ID of the app which took the thread control.
+	NewOwnerAppID AppID `json:"new_owner_app_id"`
+	// Metadata is the custom string sent by the new owner.
+	Metadata string `json:"metadata"`
+}
+
+// RequestThreadControlEvent represents the event fired when a secondary receiver
+// requests thread control from the app.
+type RequestThreadControlEvent struct {
+	// Sender is the user the thread belongs to.
+	Sender Sender `json:"-"`
+	// Recipient is the page the thread belongs to.
+	Recipient Recipient `json:"-"`
+	// Time is when the thread control was requested.
+	Time time.Time `json:"-"`
+	// Platform is the platform the thread control was requested on.
+	Platform Platform `json:"-"`
+	// RequestedOwnerAppID is the ID of the app which requested the thread control.
+	RequestedOwnerAppID AppID `json:"requested_owner_app_id"`
+	// Metadata is the custom string sent by the requesting app.
+	Metadata string `json:"metadata"`
+}
+
+// AppRoles maps the app IDs to the roles assigned to them by the page,
+// e.g. "primary_receiver" and "secondary_receiver".
+type AppRoles map[string][]string
+
+// AppRolesEvent represents the event fired when the page assigns app roles to the app.
+type AppRolesEvent struct {
+	// Sender is who the event was sent from.
+	Sender Sender
+	// Recipient is the page the roles were assigned by.
+	Recipient Recipient
+	// Time is when the roles were assigned.
+	Time time.Time
+	// Platform is the platform the roles were assigned on.
+	Platform Platform
+	// Roles are the assigned app roles.
+	Roles AppRoles
+}
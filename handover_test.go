@@ -0,0 +1,119 @@
+package messenger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessenger_DispatchHandover(t *testing.T) {
+	t.Parallel()
+
+	payload := `{
+	  "object": "page",
+	  "entry": [{
+	    "id": "222",
+	    "time": 1543095111999,
+	    "messaging": [
+	      {"sender": {"id": "111"}, "recipient": {"id": "222"}, "timestamp": 1543095111999,
+	       "pass_thread_control": {"new_owner_app_id": "123456789", "previous_owner_app_id": 987654321, "metadata": "pass"}},
+	      {"sender": {"id": "111"}, "recipient": {"id": "222"}, "timestamp": 1543095111999,
+	       "take_thread_control": {"previous_owner_app_id": "123456789", "new_owner_app_id": "987654321", "metadata": "take"}},
+	      {"sender": {"id": "111"}, "recipient": {"id": "222"}, "timestamp": 1543095111999,
+	       "request_thread_control": {"requested_owner_app_id": 123456789, "metadata": "request"}},
+	      {"recipient": {"id": "222"}, "timestamp": 1543095111999,
+	       "app_roles": {"123456789": ["primary_receiver"]}}
+	    ],
+	    "standby": [
+	      {"sender": {"id": "111"}, "recipient": {"id": "222"}, "timestamp": 1543095111999, "message": {"mid": "m1", "text": "hi"}}
+	    ]
+	  }]
+	}`
+
+	var rec Receive
+	require.NoError(t, json.Unmarshal([]byte(payload), &rec))
+
+	m := &Messenger{}
+
+	var calls []string
+	m.HandlePassThreadControl(func(e PassThreadControlEvent, r *Response) {
+		calls = append(calls, e.Metadata)
+		assert.EqualValues(t, 123456789, e.NewOwnerAppID)
+		assert.EqualValues(t, 987654321, e.PreviousOwnerAppID)
+		assert.EqualValues(t, 111, e.Sender.ID)
+	})
+	m.HandleTakeThreadControl(func(e TakeThreadControlEvent, r *Response) {
+		calls = append(calls, e.Metadata)
+		assert.EqualValues(t, 123456789, e.PreviousOwnerAppID)
+	})
+	m.HandleRequestThreadControl(func(e RequestThreadControlEvent, r *Response) {
+		calls = append(calls, e.Metadata)
+		assert.EqualValues(t, 123456789, e.RequestedOwnerAppID)
+	})
+	m.HandleAppRoles(func(e AppRolesEvent, r *Response) {
+		calls = append(calls, "roles")
+		assert.Equal(t, AppRoles{"123456789": {"primary_receiver"}}, e.Roles)
+		assert.Equal(t, MessengerPlatform, e.Platform)
+	})
+	m.ForPlatform(InstagramPlatform).HandleAppRoles(func(AppRolesEvent, *Response) {
+		t.Error("page app roles must not reach instagram handlers")
+	})
+	m.HandleStandby(func(info MessageInfo, r *Response) {
+		calls = append(calls, info.Message.Mid)
+	})
+	m.HandleMessage(func(msg Message, r *Response) {
+		t.Error("standby message must not reach message handlers")
+	})
+
+	m.dispatch(context.Background(), rec)
+	assert.Equal(t, []string{"pass", "take", "request", "roles", "m1"}, calls)
+}
+
+func TestResponse_ThreadControl(t *testing.T) {
+	t.Parallel()
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.URL.Path+" "+string(body))
+		fmt.Fprint(w, `{"success": true}`)
+	}))
+	defer srv.Close()
+
+	m := New(Options{HTTPClient: srv.Client(), GraphURL: srv.URL})
+	r := m.Response(111)
+
+	require.NoError(t, r.PassThreadControl(123456789, "to bot"))
+	require.NoError(t, r.TakeThreadControl("take"))
+	require.NoError(t, r.RequestThreadControl(""))
+	require.NoError(t, m.ReleaseThreadControl(Recipient{ID: 111}, "release"))
+
+	assert.Equal(t, []string{
+		`/v2.11/me/pass_thread_control {"recipient":{"id":"111"},"target_app_id":123456789,"metadata":"to bot"}`,
+		`/v2.11/me/take_thread_control {"recipient":{"id":"111"},"metadata":"take"}`,
+		`/v2.11/me/request_thread_control {"recipient":{"id":"111"}}`,
+		`/v2.11/me/release_thread_control {"recipient":{"id":"111"},"metadata":"release"}`,
+	}, requests)
+}
+
+func TestResponse_ThreadControlCtx(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := (&Messenger{}).Response(111)
+
+	assert.True(t, errors.Is(r.PassThreadControlCtx(ctx, 123456789, ""), context.Canceled))
+	assert.True(t, errors.Is(r.TakeThreadControlCtx(ctx, ""), context.Canceled))
+	assert.True(t, errors.Is(r.RequestThreadControlCtx(ctx, ""), context.Canceled))
+	assert.True(t, errors.Is(r.ReleaseThreadControlCtx(ctx, ""), context.Canceled))
+}
@@ -0,0 +1,77 @@
+package messenger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Logger is a leveled logger used to report the problems of webhook processing.
+// The args are alternating keys and values, so *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NopLogger is a Logger which discards everything.
+type NopLogger struct{}
+
+// Debug implements Logger.
+func (NopLogger) Debug(string, ...interface{}) {}
+
+// Info implements Logger.
+func (NopLogger) Info(string, ...interface{}) {}
+
+// Warn implements Logger.
+func (NopLogger) Warn(string, ...interface{}) {}
+
+// Error implements Logger.
+func (NopLogger) Error(string, ...interface{}) {}
+
+// writerLogger writes every record as a single "LEVEL msg key=value" line.
+type writerLogger struct {
+	w io.Writer
+}
+
+// defaultLogger is used when Options.Logger is nil. It prints to the standard output.
+var defaultLogger Logger = writerLogger{w: os.Stdout}
+
+// Debug implements Logger.
+func (l writerLogger) Debug(msg string, args ...interface{}) {
+	l.log("DEBUG", msg, args)
+}
+
+// Info implements Logger.
+func (l writerLogger) Info(msg string, args ...interface{}) {
+	l.log("INFO", msg, args)
+}
+
+// Warn implements Logger.
+func (l writerLogger) Warn(msg string, args ...interface{}) {
+	l.log("WARN", msg, args)
+}
+
+// Error implements Logger.
+func (l writerLogger) Error(msg string, args ...interface{}) {
+	l.log("ERROR", msg, args)
+}
+
+func (l writerLogger) log(level, msg string, args []interface{}) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteString(" ")
+	b.WriteString(msg)
+
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", args[i])
+		}
+	}
+
+	fmt.Fprintln(l.w, b.String())
+}
@@ -0,0 +1,42 @@
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterLogger(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	l := writerLogger{w: &b}
+
+	l.Warn("unknown webhook event", "page_id", 222, "action", UnknownAction, "dangling")
+	assert.Equal(t, "WARN unknown webhook event page_id=222 action=unknown dangling\n", b.String())
+}
+
+func TestMessenger_Logger(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	m := New(Options{Logger: writerLogger{w: &b}})
+
+	m.dispatch(context.Background(), Receive{
+		Object: "page",
+		Entry: []Entry{
+			{ID: 222, Messaging: []MessageInfo{{Sender: Sender{ID: 111}}}},
+		},
+	})
+	assert.Equal(t, "WARN unknown webhook event page_id=222 sender_id=111 platform=page action=unknown\n", b.String())
+
+	b.Reset()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{")))
+	assert.Contains(t, b.String(), "ERROR could not decode webhook request error=")
+}
@@ -13,8 +13,13 @@ type Message struct {
 	Recipient Recipient `json:"-"`
 	// Time is when the message was sent.
 	Time time.Time `json:"-"`
+	// Platform is the platform the message was sent on.
+	Platform Platform `json:"-"`
 	// Message is mine
 	IsEcho bool `json:"is_echo,omitempty"`
+	// AppID is the ID of the app which sent the echoed message.
+	// Zero if the message was sent by a human agent from the Page Inbox.
+	AppID int64 `json:"app_id,omitempty"`
 	// Mid is the ID of the message.
 	Metadata string `json:"metadata"`
 	// Mid is the ID of the message.
@@ -50,6 +55,8 @@ type Message struct {
 // Delivery represents a the event fired when Facebook delivers a message to the
 // recipient.
 type Delivery struct {
+	// Platform is the platform the message was delivered on.
+	Platform Platform `json:"-"`
 	// Mids are the IDs of the messages which were read.
 	Mids []string `json:"mids"`
 	// RawWatermark is the timestamp of when the delivery was.
@@ -61,6 +68,8 @@ type Delivery struct {
 // Read represents a the event fired when a message is read by the
 // recipient.
 type Read struct {
+	// Platform is the platform the message was read on.
+	Platform Platform `json:"-"`
 	// RawWatermark is the timestamp before which all messages have been read
 	// by the user
 	RawWatermark int64 `json:"watermark"`
@@ -78,6 +87,14 @@ type IGMessageRead struct {
 
 // IGMessageReaction represents reaction to the Instagram message.
 type IGMessageReaction struct {
+	// Sender is who the reaction was sent from.
+	Sender Sender `json:"-"`
+	// Recipient is who the reaction was sent to.
+	Recipient Recipient `json:"-"`
+	// Time is when the reaction was sent.
+	Time time.Time `json:"-"`
+	// Platform is the platform the reaction was sent on.
+	Platform Platform `json:"-"`
 	// Mid is a message ID.
 	Mid string `json:"mid"`
 	// Action can be {react|unreact}
@@ -134,6 +151,8 @@ type PostBack struct {
 	Recipient Recipient `json:"-"`
 	// Time is when the message was sent.
 	Time time.Time `json:"-"`
+	// Platform is the platform the message was sent on.
+	Platform Platform `json:"-"`
 	// PostBack ID
 	Payload string `json:"payload"`
 	// Optional referral info
@@ -151,6 +170,8 @@ type AccountLinking struct {
 	Recipient Recipient `json:"-"`
 	// Time is when the message was sent.
 	Time time.Time `json:"-"`
+	// Platform is the platform the message was sent on.
+	Platform Platform `json:"-"`
 	// Status represents the new account linking status.
 	Status string `json:"status"`
 	// AuthorizationCode is a pass-through code set during the linking process.
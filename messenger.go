@@ -2,12 +2,16 @@ package messenger
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"runtime/debug"
 	"strings"
 	"time"
 
@@ -17,18 +21,18 @@ import (
 const (
 	// ProfileURL is the API endpoint used for retrieving profiles.
 	// Used in the form: https://graph.facebook.com/v2.6/<USER_ID>?fields=<PROFILE_FIELDS>&access_token=<PAGE_ACCESS_TOKEN>
-	ProfileURL = "https://graph.facebook.com/v2.6/"
+	ProfileURL = DefaultGraphURL + "/v2.6/"
 
 	// ProfileFields is a list of JSON field names which will be populated by the profile query.
 	ProfileFields = "first_name,last_name,profile_pic"
 
 	// SendSettingsURL is API endpoint for saving settings.
-	SendSettingsURL = "https://graph.facebook.com/v2.6/me/thread_settings"
+	SendSettingsURL = DefaultGraphURL + sendSettingsPath
 
 	// MessengerProfileURL is the API endpoint where you set properties that define various aspects of the following Messenger Platform features.
 	// Used in the form https://graph.facebook.com/v2.6/me/messenger_profile?access_token=<PAGE_ACCESS_TOKEN>
 	// https://developers.facebook.com/docs/messenger-platform/reference/messenger-profile-api/
-	MessengerProfileURL = "https://graph.facebook.com/v2.6/me/messenger_profile"
+	MessengerProfileURL = DefaultGraphURL + messengerProfilePath
 )
 
 // Options are the settings used when creating a Messenger client.
@@ -37,19 +41,64 @@ type Options struct {
 	// verifying webhooks on the Facebook Developer Portal.
 	Verify bool
 	// AppSecret is the app secret from the Facebook Developer Portal. Used when
-	// in the "verify" mode.
+	// in the "verify" mode and to add appsecret_proof to every Graph API request.
+	// An AppSecretTokenSource overrides it for the tokens of another app.
 	AppSecret string
+	// AppSecrets are the additional app secrets accepted in the "verify" mode,
+	// e.g. the previous app secret during secret rotation.
+	AppSecrets []string
+	// DisableSHA1 rejects the requests signed only with the SHA-1 X-Hub-Signature header
+	// in the "verify" mode. Otherwise it is checked if X-Hub-Signature-256 is missing.
+	DisableSHA1 bool
 	// VerifyToken is the token to be used when verifying the webhook. Is set
 	// when the webhook is created.
 	VerifyToken string
 	// Token is the access token of the Facebook page to send messages from.
 	Token string
+	// TokenSource is consulted for the access token of each request instead of Token,
+	// e.g. a *CachingTokenSource refreshing the expired tokens.
+	TokenSource TokenSource
+	// Pages provides the access tokens when the Messenger serves several pages or Instagram accounts.
+	// The events are responded to with the token of the page they were sent to, Token or TokenSource
+	// is used for the pages unknown to the provider. A TokenSourceProvider provides the refreshable
+	// tokens. The events of the pages without a token are reported to OnError and not handled.
+	Pages TokenProvider
 	// WebhookURL is where the Messenger client should listen for webhook events. Leaving the string blank implies a path of "/".
 	WebhookURL string
 	// Mux is shared mux between several Messenger objects
 	Mux *http.ServeMux
 	// SendAPIVersion is a Send API version
 	SendAPIVersion string
+	// HTTPClient is used for every request to the Graph API. Leaving it nil implies http.DefaultClient.
+	HTTPClient HTTPClient
+	// GraphURL is the base URL every Graph API request is built from. Leaving the string blank implies DefaultGraphURL.
+	GraphURL string
+	// Retry enables retries of the failed Graph API requests. Leaving it nil disables retries.
+	Retry *RetryPolicy
+	// Workers enables asynchronous processing of webhook events by the given number of workers.
+	// Events from the same sender are processed in order. Zero processes events before responding to the webhook.
+	// Set DedupStore as well, the events of a partially queued request are redelivered by Facebook.
+	Workers int
+	// QueueSize is the number of events each worker can hold. When the queue is full the webhook
+	// request waits for free space and fails with 503 if it is canceled first.
+	QueueSize int
+	// SuppressEchoes prevents message echoes from reaching the MessageHandlers.
+	// Echoes are passed to the EchoHandlers anyway.
+	SuppressEchoes bool
+	// Logger is used to report the problems of webhook processing. Leaving it nil implies
+	// printing to the standard output, NopLogger silences it.
+	Logger Logger
+	// OnError is called when a handler returns an error or a handler or a middleware panics.
+	// The recovered value is nil unless it was a panic, the error is *PanicError then.
+	// Leaving it nil implies logging the failures.
+	OnError func(e Event, err error, recovered interface{})
+	// DedupStore enables the deduplication of the webhook events redelivered by Facebook.
+	// Leaving it nil processes every received event.
+	DedupStore DedupStore
+	// DedupTTL is how long the processed events are remembered. Leaving it zero implies DefaultDedupTTL.
+	DedupTTL time.Duration
+	// OnDuplicate is called for every suppressed duplicate event with its deduplication key.
+	OnDuplicate func(e Event, key string)
 }
 
 // MessageHandler is a handler used for responding to a message containing text.
@@ -74,21 +123,66 @@ type ReferralHandler func(ReferralMessage, *Response)
 // being linked or unlinked.
 type AccountLinkingHandler func(AccountLinking, *Response)
 
+// EchoHandler is a handler used for responding to an echo of a message sent by the page.
+// The Response of an echo is addressed to the recipient of the echoed message.
+type EchoHandler func(Message, *Response)
+
+// PassThreadControlHandler is a handler used to react to thread control being passed to the app.
+type PassThreadControlHandler func(PassThreadControlEvent, *Response)
+
+// TakeThreadControlHandler is a handler used to react to thread control being taken from the app.
+type TakeThreadControlHandler func(TakeThreadControlEvent, *Response)
+
+// RequestThreadControlHandler is a handler used to react to another app requesting thread control.
+type RequestThreadControlHandler func(RequestThreadControlEvent, *Response)
+
+// AppRolesHandler is a handler used to react to the page assigning app roles.
+type AppRolesHandler func(AppRolesEvent, *Response)
+
+// StandbyHandler is a handler used for the events received while the app is not the thread owner.
+type StandbyHandler func(MessageInfo, *Response)
+
+// ReactionHandler is a handler used to react to a user reacting to a message
+// or removing the reaction.
+type ReactionHandler func(IGMessageReaction, *Response)
+
 // Messenger is the client which manages communication with the Messenger Platform API.
 type Messenger struct {
+	// duplicates is accessed atomically, so it goes first to be 64-bit aligned
+	duplicates             uint64
 	mux                    *http.ServeMux
-	messageHandlers        []MessageHandler
-	deliveryHandlers       []DeliveryHandler
-	readHandlers           []ReadHandler
-	postBackHandlers       []PostBackHandler
-	optInHandlers          []OptInHandler
-	referralHandlers       []ReferralHandler
-	accountLinkingHandlers []AccountLinkingHandler
-	token                  string
+	messageHandlers        []MessageHandlerE
+	deliveryHandlers       []DeliveryHandlerE
+	readHandlers           []ReadHandlerE
+	postBackHandlers       []PostBackHandlerE
+	optInHandlers          []OptInHandlerE
+	referralHandlers       []ReferralHandlerE
+	accountLinkingHandlers []AccountLinkingHandlerE
+	reactionHandlers       []ReactionHandlerE
+	echoHandlers           []EchoHandlerE
+	passThreadHandlers     []PassThreadControlHandlerE
+	takeThreadHandlers     []TakeThreadControlHandlerE
+	requestThreadHandlers  []RequestThreadControlHandlerE
+	appRolesHandlers       []AppRolesHandlerE
+	standbyHandlers        []StandbyHandlerE
+	tokens                 TokenSource
+	pages                  TokenProvider
 	verifyHandler          func(http.ResponseWriter, *http.Request)
 	verify                 bool
 	appSecret              string
+	appSecrets             []string
+	disableSHA1            bool
 	sendAPIVersion         string
+	graph                  *graphClient
+	queue                  *eventQueue
+	suppressEchoes         bool
+	logger                 Logger
+	middlewares            []Middleware
+	onError                func(Event, error, interface{})
+	dedupStore             DedupStore
+	dedupTTL               time.Duration
+	onDuplicate            func(Event, string)
+	router                 *payloadRouter
 }
 
 // New creates a new Messenger. You pass in Options in order to affect settings.
@@ -99,10 +193,26 @@ func New(mo Options) *Messenger {
 
 	m := &Messenger{
 		mux:            mo.Mux,
-		token:          mo.Token,
+		pages:          mo.Pages,
 		verify:         mo.Verify,
 		appSecret:      mo.AppSecret,
+		appSecrets:     mo.AppSecrets,
+		disableSHA1:    mo.DisableSHA1,
 		sendAPIVersion: mo.SendAPIVersion,
+		graph:          newGraphClient(mo.HTTPClient, mo.GraphURL, mo.Retry, mo.AppSecret),
+		suppressEchoes: mo.SuppressEchoes,
+		logger:         mo.Logger,
+		onError:        mo.OnError,
+		dedupStore:     mo.DedupStore,
+		dedupTTL:       mo.DedupTTL,
+		onDuplicate:    mo.OnDuplicate,
+	}
+
+	switch {
+	case mo.TokenSource != nil:
+		m.tokens = mo.TokenSource
+	case mo.Token != "":
+		m.tokens = StaticTokenSource(mo.Token)
 	}
 
 	if mo.WebhookURL == "" {
@@ -113,6 +223,14 @@ func New(mo Options) *Messenger {
 		m.sendAPIVersion = DefaultSendAPIVersion
 	}
 
+	if m.dedupTTL == 0 {
+		m.dedupTTL = DefaultDedupTTL
+	}
+
+	if mo.Workers > 0 {
+		m.queue = newEventQueue(mo.Workers, mo.QueueSize, m.dispatchEvent)
+	}
+
 	m.verifyHandler = newVerifyHandler(mo.VerifyToken)
 	m.mux.HandleFunc(mo.WebhookURL, m.handle)
 
@@ -122,40 +240,120 @@ func New(mo Options) *Messenger {
 // HandleMessage adds a new MessageHandler to the Messenger which will be triggered
 // when a message is received by the client.
 func (m *Messenger) HandleMessage(f MessageHandler) {
-	m.messageHandlers = append(m.messageHandlers, f)
+	m.HandleMessageE(func(message Message, r *Response) error {
+		f(message, r)
+		return nil
+	})
+}
+
+// HandleEcho adds a new EchoHandler to the Messenger which will be triggered
+// when a message sent by the page is echoed back.
+func (m *Messenger) HandleEcho(f EchoHandler) {
+	m.HandleEchoE(func(echo Message, r *Response) error {
+		f(echo, r)
+		return nil
+	})
 }
 
 // HandleDelivery adds a new DeliveryHandler to the Messenger which will be triggered
 // when a previously sent message is delivered to the recipient.
 func (m *Messenger) HandleDelivery(f DeliveryHandler) {
-	m.deliveryHandlers = append(m.deliveryHandlers, f)
+	m.HandleDeliveryE(func(delivery Delivery, r *Response) error {
+		f(delivery, r)
+		return nil
+	})
 }
 
 // HandleOptIn adds a new OptInHandler to the Messenger which will be triggered
 // once a user opts in to communicate with the bot.
 func (m *Messenger) HandleOptIn(f OptInHandler) {
-	m.optInHandlers = append(m.optInHandlers, f)
+	m.HandleOptInE(func(optin OptIn, r *Response) error {
+		f(optin, r)
+		return nil
+	})
 }
 
 // HandleRead adds a new DeliveryHandler to the Messenger which will be triggered
 // when a previously sent message is read by the recipient.
 func (m *Messenger) HandleRead(f ReadHandler) {
-	m.readHandlers = append(m.readHandlers, f)
+	m.HandleReadE(func(read Read, r *Response) error {
+		f(read, r)
+		return nil
+	})
 }
 
 // HandlePostBack adds a new PostBackHandler to the Messenger.
 func (m *Messenger) HandlePostBack(f PostBackHandler) {
-	m.postBackHandlers = append(m.postBackHandlers, f)
+	m.HandlePostBackE(func(postback PostBack, r *Response) error {
+		f(postback, r)
+		return nil
+	})
 }
 
 // HandleReferral adds a new ReferralHandler to the Messenger.
 func (m *Messenger) HandleReferral(f ReferralHandler) {
-	m.referralHandlers = append(m.referralHandlers, f)
+	m.HandleReferralE(func(referral ReferralMessage, r *Response) error {
+		f(referral, r)
+		return nil
+	})
 }
 
 // HandleAccountLinking adds a new AccountLinkingHandler to the Messenger.
 func (m *Messenger) HandleAccountLinking(f AccountLinkingHandler) {
-	m.accountLinkingHandlers = append(m.accountLinkingHandlers, f)
+	m.HandleAccountLinkingE(func(linking AccountLinking, r *Response) error {
+		f(linking, r)
+		return nil
+	})
+}
+
+// HandleReaction adds a new ReactionHandler to the Messenger.
+func (m *Messenger) HandleReaction(f ReactionHandler) {
+	m.HandleReactionE(func(reaction IGMessageReaction, r *Response) error {
+		f(reaction, r)
+		return nil
+	})
+}
+
+// HandlePassThreadControl adds a new PassThreadControlHandler to the Messenger.
+func (m *Messenger) HandlePassThreadControl(f PassThreadControlHandler) {
+	m.HandlePassThreadControlE(func(e PassThreadControlEvent, r *Response) error {
+		f(e, r)
+		return nil
+	})
+}
+
+// HandleTakeThreadControl adds a new TakeThreadControlHandler to the Messenger.
+func (m *Messenger) HandleTakeThreadControl(f TakeThreadControlHandler) {
+	m.HandleTakeThreadControlE(func(e TakeThreadControlEvent, r *Response) error {
+		f(e, r)
+		return nil
+	})
+}
+
+// HandleRequestThreadControl adds a new RequestThreadControlHandler to the Messenger.
+func (m *Messenger) HandleRequestThreadControl(f RequestThreadControlHandler) {
+	m.HandleRequestThreadControlE(func(e RequestThreadControlEvent, r *Response) error {
+		f(e, r)
+		return nil
+	})
+}
+
+// HandleAppRoles adds a new AppRolesHandler to the Messenger.
+func (m *Messenger) HandleAppRoles(f AppRolesHandler) {
+	m.HandleAppRolesE(func(e AppRolesEvent, r *Response) error {
+		f(e, r)
+		return nil
+	})
+}
+
+// HandleStandby adds a new StandbyHandler to the Messenger which will be triggered
+// when an event is received while the app is a secondary receiver. The standby events
+// do not trigger any other handlers.
+func (m *Messenger) HandleStandby(f StandbyHandler) {
+	m.HandleStandbyE(func(info MessageInfo, r *Response) error {
+		f(info, r)
+		return nil
+	})
 }
 
 // Handler returns the Messenger in HTTP client form.
@@ -173,19 +371,22 @@ func (m *Messenger) Handler() http.Handler {
 // - Last Name
 // - Profile Picture.
 func (m *Messenger) ProfileByID(id int64, profileFields []string) (Profile, error) {
+	return m.ProfileByIDCtx(context.Background(), id, profileFields)
+}
+
+// ProfileByIDCtx is like ProfileByID but the request is bound to the context.
+func (m *Messenger) ProfileByIDCtx(ctx context.Context, id int64, profileFields []string) (Profile, error) {
 	p := Profile{}
-	url := fmt.Sprintf("%v%v", ProfileURL, id)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := m.graph.newRequest(ctx, "GET", fmt.Sprintf(profilePath, id), nil)
 	if err != nil {
 		return p, err
 	}
 
 	fields := strings.Join(profileFields, ",")
-	req.URL.RawQuery = "fields=" + fields + "&access_token=" + m.token
+	req.URL.RawQuery = "fields=" + fields
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := m.graph.send(req, m.tokens)
 	if err != nil {
 		return p, err
 	}
@@ -213,7 +414,14 @@ func (m *Messenger) ProfileByID(id int64, profileFields []string) (Profile, erro
 }
 
 // GreetingSetting sends settings for greeting.
+//
+// Deprecated: the thread settings API is replaced by the Messenger Profile API, use SetMessengerProfile.
 func (m *Messenger) GreetingSetting(text string) (QueryResponse, error) {
+	return m.GreetingSettingCtx(context.Background(), text)
+}
+
+// GreetingSettingCtx is like GreetingSetting but the request is bound to the context.
+func (m *Messenger) GreetingSettingCtx(ctx context.Context, text string) (QueryResponse, error) {
 	var qr QueryResponse
 
 	d := GreetingSetting{
@@ -228,17 +436,13 @@ func (m *Messenger) GreetingSetting(text string) (QueryResponse, error) {
 		return qr, err
 	}
 
-	req, err := http.NewRequest("POST", SendSettingsURL, bytes.NewBuffer(data))
+	req, err := m.graph.newRequest(ctx, "POST", sendSettingsPath, bytes.NewBuffer(data))
 	if err != nil {
 		return qr, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.URL.RawQuery = "access_token=" + m.token
-
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
+	resp, err := m.graph.send(req, m.tokens)
 	if err != nil {
 		return qr, err
 	}
@@ -248,7 +452,18 @@ func (m *Messenger) GreetingSetting(text string) (QueryResponse, error) {
 }
 
 // CallToActionsSetting sends settings for Get Started or Persistent Menu.
+//
+// Deprecated: the thread settings API is replaced by the Messenger Profile API, use SetMessengerProfile.
 func (m *Messenger) CallToActionsSetting(state string, actions []CallToActionsItem) (QueryResponse, error) {
+	return m.CallToActionsSettingCtx(context.Background(), state, actions)
+}
+
+// CallToActionsSettingCtx is like CallToActionsSetting but the request is bound to the context.
+func (m *Messenger) CallToActionsSettingCtx(
+	ctx context.Context,
+	state string,
+	actions []CallToActionsItem,
+) (QueryResponse, error) {
 	var qr QueryResponse
 
 	d := CallToActionsSetting{
@@ -262,17 +477,13 @@ func (m *Messenger) CallToActionsSetting(state string, actions []CallToActionsIt
 		return qr, err
 	}
 
-	req, err := http.NewRequest("POST", SendSettingsURL, bytes.NewBuffer(data))
+	req, err := m.graph.newRequest(ctx, "POST", sendSettingsPath, bytes.NewBuffer(data))
 	if err != nil {
 		return qr, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.URL.RawQuery = "access_token=" + m.token
-
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
+	resp, err := m.graph.send(req, m.tokens)
 	if err != nil {
 		return qr, err
 	}
@@ -296,44 +507,71 @@ func (m *Messenger) handle(w http.ResponseWriter, r *http.Request) {
 
 	err := json.Unmarshal(body, &rec)
 	if err != nil {
-		err = xerrors.Errorf("could not decode response: %w", err)
-		fmt.Println(err)
-		fmt.Println("could not decode response:", err)
+		m.log().Error("could not decode webhook request", "error", err)
 		respond(w, http.StatusBadRequest)
 		return
 	}
 
-	if rec.Object != "page" {
-		fmt.Println("Object is not page, undefined behavior. Got", rec.Object)
+	if p := Platform(rec.Object); p != MessengerPlatform && p != InstagramPlatform {
+		m.log().Warn("webhook object is not page or instagram", "object", rec.Object)
 		respond(w, http.StatusUnprocessableEntity)
 		return
 	}
 
 	if m.verify {
 		if err := m.checkIntegrity(r); err != nil {
-			fmt.Println("could not verify request:", err)
+			m.log().Warn("could not verify webhook request", "error", err)
 			respond(w, http.StatusUnauthorized)
 			return
 		}
 	}
 
-	m.dispatch(rec)
+	if m.queue != nil {
+		if err := m.enqueue(r.Context(), rec); err != nil {
+			m.log().Error("could not enqueue webhook events", "error", err)
+			respond(w, http.StatusServiceUnavailable)
+			return
+		}
+	} else {
+		m.dispatch(r.Context(), rec)
+	}
 
 	respond(w, http.StatusAccepted) // We do not return any meaningful response immediately so it should be 202
 }
 
+// log returns the logger of the Messenger.
+func (m *Messenger) log() Logger {
+	if m.logger == nil {
+		return defaultLogger
+	}
+
+	return m.logger
+}
+
+// respond writes the status code, Facebook redelivers the events of the failed requests.
 func respond(w http.ResponseWriter, code int) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	fmt.Fprintf(w, `{"code": %d, "status": "%s"}`, code, http.StatusText(code))
 }
 
-// checkIntegrity checks the integrity of the requests received.
+// checkIntegrity checks the integrity of the requests received. The SHA-256 signature is preferred,
+// the SHA-1 one is checked only if the former is missing and SHA-1 is not disabled.
 func (m *Messenger) checkIntegrity(r *http.Request) error {
-	if m.appSecret == "" {
+	secrets := m.appSecrets
+	if m.appSecret != "" {
+		secrets = append([]string{m.appSecret}, secrets...)
+	}
+
+	if len(secrets) == 0 {
 		return xerrors.New("missing app secret")
 	}
 
-	sigHeader := "X-Hub-Signature"
+	sigHeader, sigEnc, hashFunc := "X-Hub-Signature-256", "sha256", sha256.New
+	if r.Header.Get(sigHeader) == "" && !m.disableSHA1 {
+		sigHeader, sigEnc, hashFunc = "X-Hub-Signature", "sha1", sha1.New
+	}
+
 	sig := strings.SplitN(r.Header.Get(sigHeader), "=", 2)
 	if len(sig) == 1 {
 		if sig[0] == "" {
@@ -342,103 +580,284 @@ func (m *Messenger) checkIntegrity(r *http.Request) error {
 		return xerrors.Errorf("malformed %s header: %v", sigHeader, strings.Join(sig, "="))
 	}
 
-	checkSHA1 := func(body []byte, hash string) error {
-		mac := hmac.New(sha1.New, []byte(m.appSecret))
-		if mac.Write(body); fmt.Sprintf("%x", mac.Sum(nil)) != hash {
-			return xerrors.Errorf("invalid signature: %s", hash)
-		}
-		return nil
+	if !strings.EqualFold(sig[0], sigEnc) {
+		return xerrors.Errorf("unknown %s header encoding, expected %s: %s", sigHeader, sigEnc, sig[0])
+	}
+
+	hash, err := hex.DecodeString(sig[1])
+	if err != nil {
+		return xerrors.Errorf("malformed %s header: %w", sigHeader, err)
 	}
 
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
-	sigEnc := strings.ToLower(sig[0])
-	sigHash := strings.ToLower(sig[1])
-	switch sigEnc {
-	case "sha1":
-		return checkSHA1(body, sigHash)
-	default:
-		return xerrors.Errorf("unknown %s header encoding, expected sha1: %s", sigHeader, sig[0])
+	for _, secret := range secrets {
+		mac := hmac.New(hashFunc, []byte(secret))
+		if mac.Write(body); hmac.Equal(mac.Sum(nil), hash) {
+			return nil
+		}
 	}
+
+	return xerrors.Errorf("invalid signature: %s", sig[1])
 }
 
 // dispatch triggers all of the relevant handlers when a webhook event is received.
-func (m *Messenger) dispatch(r Receive) {
-	for _, entry := range r.Entry {
-		for _, info := range entry.Messaging {
-			a := m.classify(info)
-			if a == UnknownAction {
-				fmt.Println("Unknown action:", info)
-				continue
+// Responses passed to the handlers are bound to the context.
+func (m *Messenger) dispatch(ctx context.Context, r Receive) {
+	for _, info := range r.events() {
+		m.dispatchEvent(ctx, info)
+	}
+}
+
+// enqueue puts the webhook events into the worker queues. The request fails if any of the events
+// can't be queued, so Facebook redelivers the whole batch and the queued events are processed
+// again unless Options.DedupStore is set.
+func (m *Messenger) enqueue(ctx context.Context, r Receive) error {
+	for _, info := range r.events() {
+		if err := m.queue.enqueue(ctx, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Shutdown stops accepting webhook events and waits until the queued ones are processed
+// when the events are processed asynchronously (see Options.Workers). If the context is done first
+// the context of the running handlers is canceled and the context error is returned.
+func (m *Messenger) Shutdown(ctx context.Context) error {
+	if m.queue == nil {
+		return nil
+	}
+
+	return m.queue.shutdown(ctx)
+}
+
+// dispatchEvent passes a single webhook event through the middleware chain to the handlers.
+func (m *Messenger) dispatchEvent(ctx context.Context, info MessageInfo) {
+	e := Event{Info: info}
+
+	// the TokenProvider and the DedupStore panics are reported as well as the handler ones
+	defer func() {
+		if rec := recover(); rec != nil {
+			m.reportError(e, &PanicError{Value: rec, Stack: debug.Stack()}, rec)
+		}
+	}()
+
+	e.Action = m.classify(info)
+	if e.Action == UnknownAction && !info.Standby {
+		m.log().Warn("unknown webhook event",
+			"page_id", info.PageID,
+			"sender_id", info.Sender.ID,
+			"platform", info.Platform,
+			"action", e.Action,
+		)
+		return
+	}
+
+	to := Recipient{ID: info.Sender.ID}
+	if e.Action == EchoAction && !info.Standby {
+		to = info.Recipient
+	}
+	e.Response = m.newResponse(to).WithContext(ctx)
+
+	tokens, err := m.pageTokenSource(ctx, info.PageID)
+	if err != nil {
+		m.reportError(e, xerrors.Errorf("could not get page access token: %w", err), nil)
+		return
+	}
+	e.Response.tokens = tokens
+
+	if m.duplicate(ctx, e) {
+		return
+	}
+
+	// the errors of the handlers are reported by handleEvent
+	_ = m.chain(m.handleEvent)(ctx, e)
+}
+
+// handleEvent triggers all of the relevant handlers for the event. It is the innermost EventHandler.
+// A failing handler does not prevent the others from being triggered, the first failure is returned.
+func (m *Messenger) handleEvent(ctx context.Context, e Event) error {
+	info := e.Info
+	resp := e.Response.WithContext(ctx)
+
+	var err error
+	call := func(f func() error) {
+		if callErr := m.invoke(e, f); callErr != nil && err == nil {
+			err = callErr
+		}
+	}
+
+	if info.Standby {
+		for _, f := range m.standbyHandlers {
+			call(func() error { return f(info, resp) })
+		}
+		return err
+	}
+
+	switch e.Action {
+	case EchoAction, TextAction:
+		message := *info.Message
+		message.Sender = info.Sender
+		message.Recipient = info.Recipient
+		message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+		message.Platform = info.Platform
+
+		if e.Action == EchoAction {
+			for _, f := range m.echoHandlers {
+				call(func() error { return f(message, resp) })
 			}
 
-			resp := &Response{
-				to:             Recipient{ID: info.Sender.ID},
-				token:          m.token,
-				sendAPIVersion: m.sendAPIVersion,
+			if m.suppressEchoes {
+				break
 			}
+		}
+
+		for _, f := range m.messageHandlers {
+			call(func() error { return f(message, resp) })
+		}
+
+		if m.router != nil && !message.IsEcho && message.QuickReply != nil {
+			m.router.route(PayloadRequest{
+				Sender:    message.Sender,
+				Recipient: message.Recipient,
+				Time:      message.Time,
+				Platform:  message.Platform,
+				Payload:   message.QuickReply.Payload,
+				Message:   &message,
+			}, resp, call)
+		}
+	case DeliveryAction:
+		for _, f := range m.deliveryHandlers {
+			delivery := *info.Delivery
+			delivery.Platform = info.Platform
+			call(func() error { return f(delivery, resp) })
+		}
+	case ReadAction:
+		for _, f := range m.readHandlers {
+			read := *info.Read
+			read.Platform = info.Platform
+			call(func() error { return f(read, resp) })
+		}
+	case PostBackAction:
+		postBack := *info.PostBack
+		postBack.Sender = info.Sender
+		postBack.Recipient = info.Recipient
+		postBack.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+		postBack.Platform = info.Platform
+
+		for _, f := range m.postBackHandlers {
+			call(func() error { return f(postBack, resp) })
+		}
 
-			switch a {
-			case TextAction:
-				for _, f := range m.messageHandlers {
-					message := *info.Message
-					message.Sender = info.Sender
-					message.Recipient = info.Recipient
-					message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
-					f(message, resp)
-				}
-			case DeliveryAction:
-				for _, f := range m.deliveryHandlers {
-					f(*info.Delivery, resp)
-				}
-			case ReadAction:
-				for _, f := range m.readHandlers {
-					f(*info.Read, resp)
-				}
-			case PostBackAction:
-				for _, f := range m.postBackHandlers {
-					message := *info.PostBack
-					message.Sender = info.Sender
-					message.Recipient = info.Recipient
-					message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
-					f(message, resp)
-				}
-			case OptInAction:
-				for _, f := range m.optInHandlers {
-					message := *info.OptIn
-					message.Sender = info.Sender
-					message.Recipient = info.Recipient
-					message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
-					f(message, resp)
-				}
-			case ReferralAction:
-				for _, f := range m.referralHandlers {
-					message := *info.ReferralMessage
-					message.Sender = info.Sender
-					message.Recipient = info.Recipient
-					message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
-					f(message, resp)
-				}
-			case AccountLinkingAction:
-				for _, f := range m.accountLinkingHandlers {
-					message := *info.AccountLinking
-					message.Sender = info.Sender
-					message.Recipient = info.Recipient
-					message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
-					f(message, resp)
-				}
+		if m.router != nil {
+			m.router.route(PayloadRequest{
+				Sender:    postBack.Sender,
+				Recipient: postBack.Recipient,
+				Time:      postBack.Time,
+				Platform:  postBack.Platform,
+				Payload:   postBack.Payload,
+				PostBack:  &postBack,
+			}, resp, call)
+		}
+	case OptInAction:
+		for _, f := range m.optInHandlers {
+			message := *info.OptIn
+			message.Sender = info.Sender
+			message.Recipient = info.Recipient
+			message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+			message.Platform = info.Platform
+			call(func() error { return f(message, resp) })
+		}
+	case ReferralAction:
+		for _, f := range m.referralHandlers {
+			message := *info.ReferralMessage
+			message.Sender = info.Sender
+			message.Recipient = info.Recipient
+			message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+			message.Platform = info.Platform
+			call(func() error { return f(message, resp) })
+		}
+	case AccountLinkingAction:
+		for _, f := range m.accountLinkingHandlers {
+			message := *info.AccountLinking
+			message.Sender = info.Sender
+			message.Recipient = info.Recipient
+			message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+			message.Platform = info.Platform
+			call(func() error { return f(message, resp) })
+		}
+	case MessageReactionAction:
+		for _, f := range m.reactionHandlers {
+			message := *info.Reaction
+			message.Sender = info.Sender
+			message.Recipient = info.Recipient
+			message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+			message.Platform = info.Platform
+			call(func() error { return f(message, resp) })
+		}
+	case PassThreadControlAction:
+		for _, f := range m.passThreadHandlers {
+			message := *info.PassThreadControl
+			message.Sender = info.Sender
+			message.Recipient = info.Recipient
+			message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+			message.Platform = info.Platform
+			call(func() error { return f(message, resp) })
+		}
+	case TakeThreadControlAction:
+		for _, f := range m.takeThreadHandlers {
+			message := *info.TakeThreadControl
+			message.Sender = info.Sender
+			message.Recipient = info.Recipient
+			message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+			message.Platform = info.Platform
+			call(func() error { return f(message, resp) })
+		}
+	case RequestThreadControlAction:
+		for _, f := range m.requestThreadHandlers {
+			message := *info.RequestThreadControl
+			message.Sender = info.Sender
+			message.Recipient = info.Recipient
+			message.Time = time.Unix(info.Timestamp/int64(time.Microsecond), 0)
+			message.Platform = info.Platform
+			call(func() error { return f(message, resp) })
+		}
+	case AppRolesAction:
+		for _, f := range m.appRolesHandlers {
+			e := AppRolesEvent{
+				Sender:    info.Sender,
+				Recipient: info.Recipient,
+				Time:      time.Unix(info.Timestamp/int64(time.Microsecond), 0),
+				Platform:  info.Platform,
+				Roles:     info.AppRoles,
 			}
+			call(func() error { return f(e, resp) })
 		}
 	}
+
+	return err
 }
 
 // Response returns new Response object.
 func (m *Messenger) Response(to int64) *Response {
+	return m.newResponse(Recipient{ID: to})
+}
+
+// ResponseWithContext returns new Response object bound to the context.
+func (m *Messenger) ResponseWithContext(ctx context.Context, to int64) *Response {
+	return m.newResponse(Recipient{ID: to}).WithContext(ctx)
+}
+
+// newResponse returns new Response object for the recipient.
+func (m *Messenger) newResponse(to Recipient) *Response {
 	return &Response{
-		to:             Recipient{ID: to},
-		token:          m.token,
+		to:             to,
+		tokens:         m.tokens,
 		sendAPIVersion: m.sendAPIVersion,
+		graph:          m.graph,
 	}
 }
 
@@ -463,11 +882,7 @@ func (m *Messenger) SendGeneralMessage(
 	metadata string,
 	tags ...string,
 ) (QueryResponse, error) {
-	r := &Response{
-		token:          m.token,
-		to:             to,
-		sendAPIVersion: m.sendAPIVersion,
-	}
+	r := m.newResponse(to)
 	return r.GenericTemplate(elements, messagingType, control, metadata, tags...)
 }
 
@@ -481,11 +896,7 @@ func (m *Messenger) SendWithReplies(
 	metadata string,
 	tags ...string,
 ) (QueryResponse, error) {
-	response := &Response{
-		token:          m.token,
-		to:             to,
-		sendAPIVersion: m.sendAPIVersion,
-	}
+	response := m.newResponse(to)
 
 	return response.TextWithReplies(message, replies, messagingType, control, metadata, tags...)
 }
@@ -500,17 +911,18 @@ func (m *Messenger) Attachment(
 	metadata string,
 	tags ...string,
 ) (QueryResponse, error) {
-	response := &Response{
-		token:          m.token,
-		to:             to,
-		sendAPIVersion: m.sendAPIVersion,
-	}
+	response := m.newResponse(to)
 
 	return response.Attachment(dataType, url, messagingType, control, metadata, tags...)
 }
 
 // EnableChatExtension set the homepage url required for a chat extension.
 func (m *Messenger) EnableChatExtension(homeURL HomeURL) error {
+	return m.EnableChatExtensionCtx(context.Background(), homeURL)
+}
+
+// EnableChatExtensionCtx is like EnableChatExtension but the request is bound to the context.
+func (m *Messenger) EnableChatExtensionCtx(ctx context.Context, homeURL HomeURL) error {
 	wrap := map[string]interface{}{
 		"home_url": homeURL,
 	}
@@ -519,31 +931,11 @@ func (m *Messenger) EnableChatExtension(homeURL HomeURL) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", MessengerProfileURL, bytes.NewBuffer(data))
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.URL.RawQuery = "access_token=" + m.token
-
-	client := &http.Client{}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	return checkFacebookError(resp.Body)
+	return m.sendMessengerProfile(ctx, "POST", bytes.NewBuffer(data))
 }
 
 func (m *Messenger) SenderAction(to Recipient, action SenderAction) (QueryResponse, error) {
-	response := &Response{
-		token:          m.token,
-		to:             to,
-		sendAPIVersion: m.sendAPIVersion,
-	}
+	response := m.newResponse(to)
 	return response.SenderAction(action)
 }
 
@@ -553,17 +945,56 @@ func (m *Messenger) InstagramReaction(
 	action ReactionAction,
 	reaction ...string,
 ) (QueryResponse, error) {
-	response := &Response{
-		token:          m.token,
-		to:             to,
-		sendAPIVersion: m.sendAPIVersion,
-	}
+	response := m.newResponse(to)
 	return response.InstagramReaction(mid, action, reaction...)
 }
 
+// PassThreadControl passes thread control of the recipient to the app.
+func (m *Messenger) PassThreadControl(to Recipient, targetAppID int64, metadata string) error {
+	return m.PassThreadControlCtx(context.Background(), to, targetAppID, metadata)
+}
+
+// PassThreadControlCtx is like PassThreadControl but the request is bound to the context.
+func (m *Messenger) PassThreadControlCtx(ctx context.Context, to Recipient, targetAppID int64, metadata string) error {
+	return m.newResponse(to).PassThreadControlCtx(ctx, targetAppID, metadata)
+}
+
+// TakeThreadControl takes thread control of the recipient from the app which owns it.
+func (m *Messenger) TakeThreadControl(to Recipient, metadata string) error {
+	return m.TakeThreadControlCtx(context.Background(), to, metadata)
+}
+
+// TakeThreadControlCtx is like TakeThreadControl but the request is bound to the context.
+func (m *Messenger) TakeThreadControlCtx(ctx context.Context, to Recipient, metadata string) error {
+	return m.newResponse(to).TakeThreadControlCtx(ctx, metadata)
+}
+
+// RequestThreadControl asks the primary receiver to pass thread control of the recipient to the app.
+func (m *Messenger) RequestThreadControl(to Recipient, metadata string) error {
+	return m.RequestThreadControlCtx(context.Background(), to, metadata)
+}
+
+// RequestThreadControlCtx is like RequestThreadControl but the request is bound to the context.
+func (m *Messenger) RequestThreadControlCtx(ctx context.Context, to Recipient, metadata string) error {
+	return m.newResponse(to).RequestThreadControlCtx(ctx, metadata)
+}
+
+// ReleaseThreadControl releases thread control of the recipient back to the primary receiver.
+func (m *Messenger) ReleaseThreadControl(to Recipient, metadata string) error {
+	return m.ReleaseThreadControlCtx(context.Background(), to, metadata)
+}
+
+// ReleaseThreadControlCtx is like ReleaseThreadControl but the request is bound to the context.
+func (m *Messenger) ReleaseThreadControlCtx(ctx context.Context, to Recipient, metadata string) error {
+	return m.newResponse(to).ReleaseThreadControlCtx(ctx, metadata)
+}
+
 // classify determines what type of message a webhook event is.
 func (m *Messenger) classify(info MessageInfo) Action {
 	if info.Message != nil {
+		if info.Message.IsEcho {
+			return EchoAction
+		}
 		return TextAction
 	} else if info.Delivery != nil {
 		return DeliveryAction
@@ -577,6 +1008,16 @@ func (m *Messenger) classify(info MessageInfo) Action {
 		return ReferralAction
 	} else if info.AccountLinking != nil {
 		return AccountLinkingAction
+	} else if info.Reaction != nil {
+		return MessageReactionAction
+	} else if info.PassThreadControl != nil {
+		return PassThreadControlAction
+	} else if info.TakeThreadControl != nil {
+		return TakeThreadControlAction
+	} else if info.RequestThreadControl != nil {
+		return RequestThreadControlAction
+	} else if info.AppRoles != nil {
+		return AppRolesAction
 	}
 	return UnknownAction
 }
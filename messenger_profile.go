@@ -0,0 +1,313 @@
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ProfileField is a field of the Messenger Profile.
+type ProfileField string
+
+const (
+	// GreetingField is the greeting shown before the user starts the conversation.
+	GreetingField ProfileField = "greeting"
+	// GetStartedField is the Get Started button.
+	GetStartedField ProfileField = "get_started"
+	// PersistentMenuField is the persistent menu.
+	PersistentMenuField ProfileField = "persistent_menu"
+	// IceBreakersField is the list of the frequently asked questions.
+	IceBreakersField ProfileField = "ice_breakers"
+	// WhitelistedDomainsField is the list of the domains allowed in the webviews and the plugins.
+	WhitelistedDomainsField ProfileField = "whitelisted_domains"
+	// AccountLinkingURLField is the URL of the account linking flow.
+	AccountLinkingURLField ProfileField = "account_linking_url"
+	// HomeURLField is the home URL of the chat extension.
+	HomeURLField ProfileField = "home_url"
+)
+
+// MessengerProfileFields are all of the fields of the Messenger Profile.
+var MessengerProfileFields = []ProfileField{
+	GreetingField,
+	GetStartedField,
+	PersistentMenuField,
+	IceBreakersField,
+	WhitelistedDomainsField,
+	AccountLinkingURLField,
+	HomeURLField,
+}
+
+// The documented limits of the Messenger Profile.
+const (
+	// MaxGreetingLength is the maximal length of a greeting.
+	MaxGreetingLength = 160
+	// MaxMenuItems is the maximal number of the items of a persistent menu or a nested menu.
+	MaxMenuItems = 20
+	// MaxMenuItemTitleLength is the maximal length of a menu item title.
+	MaxMenuItemTitleLength = 30
+	// MaxIceBreakers is the maximal number of the ice breakers of a locale.
+	MaxIceBreakers = 4
+	// MaxIceBreakerQuestionLength is the maximal length of an ice breaker question.
+	MaxIceBreakerQuestionLength = 80
+	// MaxWhitelistedDomains is the maximal number of the whitelisted domains.
+	MaxWhitelistedDomains = 50
+)
+
+// DefaultLocale is the locale of the greetings, the menus and the ice breakers used when there's
+// no variant for the locale of the user.
+const DefaultLocale = "default"
+
+// MessengerProfile is the Messenger Profile of the page.
+// The empty fields are left intact by SetMessengerProfile.
+type MessengerProfile struct {
+	Greeting           []Greeting             `json:"greeting,omitempty"`
+	GetStarted         *GetStarted            `json:"get_started,omitempty"`
+	PersistentMenu     []PersistentMenu       `json:"persistent_menu,omitempty"`
+	IceBreakers        []LocalizedIceBreakers `json:"ice_breakers,omitempty"`
+	WhitelistedDomains []string               `json:"whitelisted_domains,omitempty"`
+	AccountLinkingURL  string                 `json:"account_linking_url,omitempty"`
+	HomeURL            *HomeURL               `json:"home_url,omitempty"`
+}
+
+// Greeting is the greeting of a locale. The text may contain the {{user_first_name}},
+// {{user_last_name}} and {{user_full_name}} placeholders.
+type Greeting struct {
+	Locale string `json:"locale"`
+	Text   string `json:"text"`
+}
+
+// GetStarted is the Get Started button sending the postback with the payload.
+type GetStarted struct {
+	Payload string `json:"payload"`
+}
+
+// PersistentMenu is the persistent menu of a locale.
+type PersistentMenu struct {
+	Locale string `json:"locale"`
+	// ComposerInputDisabled leaves the menu the only way for the user to interact with the bot.
+	ComposerInputDisabled bool       `json:"composer_input_disabled"`
+	CallToActions         []MenuItem `json:"call_to_actions,omitempty"`
+	// DisabledSurfaces hides the menu on the surfaces, e.g. "customer_chat_plugin".
+	DisabledSurfaces []string `json:"disabled_surfaces,omitempty"`
+}
+
+// MenuItem is an item of a persistent menu: a postback, a web_url or a nested menu.
+type MenuItem struct {
+	Type                string     `json:"type"`
+	Title               string     `json:"title"`
+	Payload             string     `json:"payload,omitempty"`
+	URL                 string     `json:"url,omitempty"`
+	WebviewHeightRatio  string     `json:"webview_height_ratio,omitempty"`
+	MessengerExtensions bool       `json:"messenger_extensions,omitempty"`
+	FallbackURL         string     `json:"fallback_url,omitempty"`
+	WebviewShareButton  string     `json:"webview_share_button,omitempty"`
+	CallToActions       []MenuItem `json:"call_to_actions,omitempty"`
+}
+
+// LocalizedIceBreakers are the ice breakers of a locale.
+type LocalizedIceBreakers struct {
+	Locale        string       `json:"locale"`
+	CallToActions []IceBreaker `json:"call_to_actions"`
+}
+
+// IceBreaker is a frequently asked question sending the postback with the payload.
+type IceBreaker struct {
+	Question string `json:"question"`
+	Payload  string `json:"payload"`
+}
+
+// Validate checks the profile against the documented limits of the Messenger Profile API.
+// It returns a *ValidationError listing every violation.
+func (p *MessengerProfile) Validate() error {
+	v := &validator{subject: "profile"}
+
+	for i, g := range p.Greeting {
+		f := fmt.Sprintf("greeting[%d]", i)
+		v.required(f+".locale", g.Locale)
+		v.required(f+".text", g.Text)
+		v.maxLength(f+".text", g.Text, MaxGreetingLength)
+	}
+
+	if p.GetStarted != nil {
+		v.required("get_started.payload", p.GetStarted.Payload)
+		v.maxLength("get_started.payload", p.GetStarted.Payload, MaxPayloadLength)
+	}
+
+	for i, menu := range p.PersistentMenu {
+		f := fmt.Sprintf("persistent_menu[%d]", i)
+		v.required(f+".locale", menu.Locale)
+		if !menu.ComposerInputDisabled && len(menu.CallToActions) == 0 {
+			v.add(f+".call_to_actions", "at least 1 item is required")
+		}
+		v.menuItems(f+".call_to_actions", menu.CallToActions)
+	}
+
+	for i, ib := range p.IceBreakers {
+		f := fmt.Sprintf("ice_breakers[%d]", i)
+		v.required(f+".locale", ib.Locale)
+		if len(ib.CallToActions) > MaxIceBreakers {
+			v.add(f+".call_to_actions", "%d ice breakers, at most %d allowed", len(ib.CallToActions), MaxIceBreakers)
+		}
+		for j, q := range ib.CallToActions {
+			qf := fmt.Sprintf("%s.call_to_actions[%d]", f, j)
+			v.required(qf+".question", q.Question)
+			v.maxLength(qf+".question", q.Question, MaxIceBreakerQuestionLength)
+			v.required(qf+".payload", q.Payload)
+			v.maxLength(qf+".payload", q.Payload, MaxPayloadLength)
+		}
+	}
+
+	if len(p.WhitelistedDomains) > MaxWhitelistedDomains {
+		v.add("whitelisted_domains", "%d domains, at most %d allowed", len(p.WhitelistedDomains), MaxWhitelistedDomains)
+	}
+	for i, d := range p.WhitelistedDomains {
+		v.https(fmt.Sprintf("whitelisted_domains[%d]", i), d)
+	}
+
+	if p.AccountLinkingURL != "" {
+		v.https("account_linking_url", p.AccountLinkingURL)
+	}
+
+	if p.HomeURL != nil {
+		v.https("home_url.url", p.HomeURL.URL)
+	}
+
+	return v.err()
+}
+
+func (v *validator) menuItems(field string, items []MenuItem) {
+	if len(items) > MaxMenuItems {
+		v.add(field, "%d items, at most %d allowed", len(items), MaxMenuItems)
+	}
+
+	for i, item := range items {
+		f := fmt.Sprintf("%s[%d]", field, i)
+
+		v.required(f+".title", item.Title)
+		v.maxLength(f+".title", item.Title, MaxMenuItemTitleLength)
+
+		switch item.Type {
+		case "postback":
+			v.required(f+".payload", item.Payload)
+			v.maxLength(f+".payload", item.Payload, MaxPayloadLength)
+		case "web_url":
+			v.required(f+".url", item.URL)
+		case "nested":
+			if len(item.CallToActions) == 0 {
+				v.add(f+".call_to_actions", "at least 1 item is required")
+			}
+			v.menuItems(f+".call_to_actions", item.CallToActions)
+		case "":
+			v.add(f+".type", "is required")
+		default:
+			v.add(f+".type", "%q is not postback, web_url or nested", item.Type)
+		}
+	}
+}
+
+// https checks the value is an https URL.
+func (v *validator) https(field, value string) {
+	if !strings.HasPrefix(value, "https://") {
+		v.add(field, "%q is not an https URL", value)
+	}
+}
+
+// GetMessengerProfile returns the fields of the Messenger Profile of the page, all of them when none are given.
+func (m *Messenger) GetMessengerProfile(ctx context.Context, fields ...ProfileField) (MessengerProfile, error) {
+	var p MessengerProfile
+
+	if len(fields) == 0 {
+		fields = MessengerProfileFields
+	}
+
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = string(f)
+	}
+
+	req, err := m.graph.newRequest(ctx, "GET", messengerProfilePath, nil)
+	if err != nil {
+		return p, err
+	}
+
+	req.URL.RawQuery = "fields=" + strings.Join(names, ",")
+
+	resp, err := m.graph.send(req, m.tokens)
+	if err != nil {
+		return p, err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return p, err
+	}
+
+	var result struct {
+		Data  []MessengerProfile `json:"data"`
+		Error *QueryError        `json:"error"`
+	}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return p, NewUnmarshalError(err).WithContent(content)
+	}
+
+	if result.Error != nil {
+		return p, result.Error
+	}
+
+	if len(result.Data) > 0 {
+		p = result.Data[0]
+	}
+
+	return p, nil
+}
+
+// SetMessengerProfile sets the non-empty fields of the profile leaving the others intact.
+// Nothing is sent when the profile is invalid, the *ValidationError lists every violation then.
+func (m *Messenger) SetMessengerProfile(ctx context.Context, p MessengerProfile) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return m.sendMessengerProfile(ctx, "POST", bytes.NewBuffer(data))
+}
+
+// DeleteMessengerProfile deletes the fields of the Messenger Profile of the page.
+func (m *Messenger) DeleteMessengerProfile(ctx context.Context, fields ...ProfileField) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(map[string][]ProfileField{"fields": fields})
+	if err != nil {
+		return err
+	}
+
+	return m.sendMessengerProfile(ctx, "DELETE", bytes.NewBuffer(data))
+}
+
+// sendMessengerProfile posts or deletes the fields of the Messenger Profile.
+func (m *Messenger) sendMessengerProfile(ctx context.Context, method string, body io.Reader) error {
+	req, err := m.graph.newRequest(ctx, method, messengerProfilePath, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.graph.send(req, m.tokens)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkFacebookError(resp.Body)
+}
@@ -0,0 +1,118 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessenger_GetMessengerProfile(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/v2.6/me/messenger_profile", r.URL.Path)
+		assert.Equal(t, "greeting,persistent_menu", r.URL.Query().Get("fields"))
+		assert.Equal(t, "token", r.URL.Query().Get("access_token"))
+		fmt.Fprint(w, `{"data": [{
+			"greeting": [{"locale": "default", "text": "Hello"}, {"locale": "ru_RU", "text": "Привет"}],
+			"persistent_menu": [{
+				"locale": "default",
+				"composer_input_disabled": true,
+				"call_to_actions": [
+					{"type": "postback", "title": "Orders", "payload": "ORDERS"},
+					{"type": "nested", "title": "More", "call_to_actions": [
+						{"type": "web_url", "title": "Site", "url": "https://example.com"}
+					]}
+				]
+			}]
+		}]}`)
+	}))
+	defer srv.Close()
+
+	m := New(Options{Token: "token", HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	p, err := m.GetMessengerProfile(context.Background(), GreetingField, PersistentMenuField)
+	require.NoError(t, err)
+	assert.Equal(t, MessengerProfile{
+		Greeting: []Greeting{{Locale: DefaultLocale, Text: "Hello"}, {Locale: "ru_RU", Text: "Привет"}},
+		PersistentMenu: []PersistentMenu{{
+			Locale:                DefaultLocale,
+			ComposerInputDisabled: true,
+			CallToActions: []MenuItem{
+				{Type: "postback", Title: "Orders", Payload: "ORDERS"},
+				{Type: "nested", Title: "More", CallToActions: []MenuItem{
+					{Type: "web_url", Title: "Site", URL: "https://example.com"},
+				}},
+			},
+		}},
+	}, p)
+}
+
+func TestMessenger_SetMessengerProfile(t *testing.T) {
+	t.Parallel()
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, r.Method+" "+string(body))
+		fmt.Fprint(w, `{"result": "success"}`)
+	}))
+	defer srv.Close()
+
+	m := New(Options{Token: "token", HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	err := m.SetMessengerProfile(context.Background(), MessengerProfile{
+		GetStarted: &GetStarted{Payload: "START"},
+		IceBreakers: []LocalizedIceBreakers{{
+			Locale:        DefaultLocale,
+			CallToActions: []IceBreaker{{Question: "Where is my order?", Payload: "ORDER_STATUS"}},
+		}},
+		WhitelistedDomains: []string{"https://example.com"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, m.DeleteMessengerProfile(context.Background(), GreetingField, HomeURLField))
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, `POST {"get_started":{"payload":"START"},`+
+		`"ice_breakers":[{"locale":"default","call_to_actions":[{"question":"Where is my order?","payload":"ORDER_STATUS"}]}],`+
+		`"whitelisted_domains":["https://example.com"]}`, requests[0])
+	assert.Equal(t, `DELETE {"fields":["greeting","home_url"]}`, requests[1])
+}
+
+func TestMessengerProfile_Validate(t *testing.T) {
+	t.Parallel()
+
+	p := MessengerProfile{
+		Greeting: []Greeting{{Locale: DefaultLocale}},
+		PersistentMenu: []PersistentMenu{{
+			Locale: DefaultLocale,
+			CallToActions: []MenuItem{
+				{Type: "nested", Title: "More"},
+				{Type: "postback", Title: "A title which is way too long for a menu", Payload: "P"},
+			},
+		}},
+		WhitelistedDomains: []string{"http://example.com"},
+	}
+
+	err := (&Messenger{}).SetMessengerProfile(context.Background(), p)
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr), err)
+	assert.Equal(t, []Violation{
+		{Field: "greeting[0].text", Message: "is required"},
+		{Field: "persistent_menu[0].call_to_actions[0].call_to_actions", Message: "at least 1 item is required"},
+		{Field: "persistent_menu[0].call_to_actions[1].title", Message: "40 characters, at most 30 allowed"},
+		{Field: "whitelisted_domains[0]", Message: `"http://example.com" is not an https URL`},
+	}, verr.Violations)
+	assert.Contains(t, err.Error(), "invalid profile: greeting[0].text: is required")
+}
@@ -1,6 +1,15 @@
 package messenger
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,6 +33,12 @@ func TestMessenger_Classify(t *testing.T) {
 			},
 			expected: TextAction,
 		},
+		"echo": {
+			msgInfo: MessageInfo{
+				Message: &Message{IsEcho: true},
+			},
+			expected: EchoAction,
+		},
 		"delivery": {
 			msgInfo: MessageInfo{
 				Delivery: &Delivery{},
@@ -54,6 +69,12 @@ func TestMessenger_Classify(t *testing.T) {
 			},
 			expected: ReferralAction,
 		},
+		"reaction": {
+			msgInfo: MessageInfo{
+				Reaction: &IGMessageReaction{},
+			},
+			expected: MessageReactionAction,
+		},
 	} {
 		t.Run("action "+name, func(t *testing.T) {
 			action := m.classify(test.msgInfo)
@@ -70,6 +91,8 @@ func TestMessenger_Dispatch(t *testing.T) {
 		read     int
 		postback int
 		referral int
+		reaction int
+		echo     int
 	}
 
 	assertHandlersCalls := func(t *testing.T, actual *handlersCalls, expected handlersCalls) {
@@ -79,6 +102,8 @@ func TestMessenger_Dispatch(t *testing.T) {
 		assert.Equal(t, actual.read, expected.read)
 		assert.Equal(t, actual.postback, expected.postback)
 		assert.Equal(t, actual.referral, expected.referral)
+		assert.Equal(t, actual.reaction, expected.reaction)
+		assert.Equal(t, actual.echo, expected.echo)
 	}
 
 	newReceive := func(msgInfo []MessageInfo) Receive {
@@ -116,13 +141,13 @@ func TestMessenger_Dispatch(t *testing.T) {
 		// First handler
 		m.HandleMessage(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{message: 1})
 
 		// Another handler
 		m.HandleMessage(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{message: 3})
 	})
 
@@ -148,13 +173,13 @@ func TestMessenger_Dispatch(t *testing.T) {
 		// First handler
 		m.HandleDelivery(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{delivery: 1})
 
 		// Another handler
 		m.HandleDelivery(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{delivery: 3})
 	})
 
@@ -180,13 +205,13 @@ func TestMessenger_Dispatch(t *testing.T) {
 		// First handler
 		m.HandleRead(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{read: 1})
 
 		// Another handler
 		m.HandleRead(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{read: 3})
 	})
 
@@ -215,13 +240,13 @@ func TestMessenger_Dispatch(t *testing.T) {
 		// First handler
 		m.HandlePostBack(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{postback: 1})
 
 		// Another handler
 		m.HandlePostBack(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{postback: 3})
 	})
 
@@ -250,13 +275,13 @@ func TestMessenger_Dispatch(t *testing.T) {
 		// First handler
 		m.HandleOptIn(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{optin: 1})
 
 		// Another handler
 		m.HandleOptIn(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{optin: 3})
 	})
 
@@ -285,13 +310,160 @@ func TestMessenger_Dispatch(t *testing.T) {
 		// First handler
 		m.HandleReferral(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{referral: 1})
 
 		// Another handler
 		m.HandleReferral(handler)
 
-		m.dispatch(newReceive(messages))
+		m.dispatch(context.Background(), newReceive(messages))
 		assertHandlersCalls(t, h, handlersCalls{referral: 3})
 	})
+	t.Run("reaction handlers", func(t *testing.T) {
+		m := &Messenger{}
+		h := &handlersCalls{}
+
+		handler := func(msg IGMessageReaction, r *Response) {
+			h.reaction++
+			assert.NotNil(t, r)
+			assert.EqualValues(t, 111, msg.Sender.ID)
+			assert.EqualValues(t, 222, msg.Recipient.ID)
+			assert.Equal(t, time.Unix(1543095111, 0), msg.Time)
+			assert.Equal(t, ReactionActionReact, msg.Action)
+		}
+
+		messages := []MessageInfo{
+			{
+				Sender:    Sender{111},
+				Recipient: Recipient{ID: 222},
+				// 2018-11-24 21:31:51 UTC + 999ms
+				Timestamp: 1543095111999,
+				Reaction:  &IGMessageReaction{Mid: "mid", Action: ReactionActionReact, Reaction: "love"},
+			},
+		}
+
+		// First handler
+		m.HandleReaction(handler)
+
+		m.dispatch(context.Background(), newReceive(messages))
+		assertHandlersCalls(t, h, handlersCalls{reaction: 1})
+
+		// Another handler
+		m.HandleReaction(handler)
+
+		m.dispatch(context.Background(), newReceive(messages))
+		assertHandlersCalls(t, h, handlersCalls{reaction: 3})
+	})
+	t.Run("echo handlers", func(t *testing.T) {
+		m := &Messenger{}
+		h := &handlersCalls{}
+
+		m.HandleEcho(func(msg Message, r *Response) {
+			h.echo++
+			assert.EqualValues(t, 222, msg.Sender.ID)
+			assert.EqualValues(t, 111, msg.Recipient.ID)
+			assert.EqualValues(t, 1517776481860111, msg.AppID)
+			assert.EqualValues(t, 111, r.to.ID)
+		})
+		m.HandleMessage(func(msg Message, r *Response) {
+			h.message++
+			assert.True(t, msg.IsEcho)
+		})
+
+		messages := []MessageInfo{
+			{
+				Sender:    Sender{222},
+				Recipient: Recipient{ID: 111},
+				// 2018-11-24 21:31:51 UTC + 999ms
+				Timestamp: 1543095111999,
+				Message:   &Message{IsEcho: true, AppID: 1517776481860111},
+			},
+		}
+
+		m.dispatch(context.Background(), newReceive(messages))
+		assertHandlersCalls(t, h, handlersCalls{echo: 1, message: 1})
+
+		m.suppressEchoes = true
+
+		m.dispatch(context.Background(), newReceive(messages))
+		assertHandlersCalls(t, h, handlersCalls{echo: 2, message: 1})
+	})
+}
+
+func TestMessenger_CheckIntegrity(t *testing.T) {
+	body := `{"object":"page","entry":[]}`
+	sign := func(h func() hash.Hash, secret string) string {
+		mac := hmac.New(h, []byte(secret))
+		mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	for name, test := range map[string]struct {
+		options Options
+		headers map[string]string
+		valid   bool
+	}{
+		"sha256": {
+			options: Options{AppSecret: "secret"},
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "secret")},
+			valid:   true,
+		},
+		"sha256 preferred over sha1": {
+			options: Options{AppSecret: "secret"},
+			headers: map[string]string{
+				"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "other"),
+				"X-Hub-Signature":     "sha1=" + sign(sha1.New, "secret"),
+			},
+			valid: false,
+		},
+		"sha1 fallback": {
+			options: Options{AppSecret: "secret"},
+			headers: map[string]string{"X-Hub-Signature": "sha1=" + sign(sha1.New, "secret")},
+			valid:   true,
+		},
+		"sha1 disabled": {
+			options: Options{AppSecret: "secret", DisableSHA1: true},
+			headers: map[string]string{"X-Hub-Signature": "sha1=" + sign(sha1.New, "secret")},
+			valid:   false,
+		},
+		"rotated secret": {
+			options: Options{AppSecret: "new", AppSecrets: []string{"old"}},
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "old")},
+			valid:   true,
+		},
+		"invalid signature": {
+			options: Options{AppSecret: "secret"},
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "other")},
+			valid:   false,
+		},
+		"malformed signature": {
+			options: Options{AppSecret: "secret"},
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=zz"},
+			valid:   false,
+		},
+		"missing signature": {
+			options: Options{AppSecret: "secret"},
+			valid:   false,
+		},
+		"missing secret": {
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "")},
+			valid:   false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := New(test.options)
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+
+			err := m.checkIntegrity(r)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
 }
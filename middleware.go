@@ -0,0 +1,36 @@
+package messenger
+
+import "context"
+
+// Event is a webhook event passed through the middleware chain.
+type Event struct {
+	// Action is the kind of the event.
+	Action Action
+	// Info is the decoded webhook event.
+	Info MessageInfo
+	// Response is used for responding to the event.
+	Response *Response
+}
+
+// EventHandler processes a webhook event. The Response passed to the registered handlers
+// is bound to the context.
+type EventHandler func(ctx context.Context, e Event) error
+
+// Middleware wraps an EventHandler to run code around the handlers of every event.
+// A middleware may short-circuit the event by not calling next, call it with an enriched
+// context and observe the returned error.
+type Middleware func(next EventHandler) EventHandler
+
+// Use adds middlewares to the Messenger. The first middleware is the outermost one.
+func (m *Messenger) Use(mw ...Middleware) {
+	m.middlewares = append(m.middlewares, mw...)
+}
+
+// chain wraps the handler into the middlewares.
+func (m *Messenger) chain(h EventHandler) EventHandler {
+	for i := len(m.middlewares) - 1; i >= 0; i-- {
+		h = m.middlewares[i](h)
+	}
+
+	return h
+}
@@ -0,0 +1,52 @@
+package messenger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessenger_Use(t *testing.T) {
+	t.Parallel()
+
+	type ctxKey struct{}
+
+	m := &Messenger{}
+
+	var calls []string
+	m.Use(
+		func(next EventHandler) EventHandler {
+			return func(ctx context.Context, e Event) error {
+				calls = append(calls, "outer "+e.Action.String())
+				return next(context.WithValue(ctx, ctxKey{}, "value"), e)
+			}
+		},
+		func(next EventHandler) EventHandler {
+			return func(ctx context.Context, e Event) error {
+				calls = append(calls, "inner "+e.Action.String())
+				if e.Action == DeliveryAction {
+					return nil
+				}
+				return next(ctx, e)
+			}
+		},
+	)
+
+	m.HandleMessage(func(msg Message, r *Response) {
+		calls = append(calls, "handler")
+		assert.Equal(t, "value", r.Context().Value(ctxKey{}))
+	})
+	m.HandleDelivery(func(d Delivery, r *Response) {
+		t.Error("delivery must be short-circuited")
+	})
+
+	m.dispatch(context.Background(), Receive{
+		Entry: []Entry{{Messaging: []MessageInfo{
+			{Message: &Message{}},
+			{Delivery: &Delivery{}},
+		}}},
+	})
+
+	assert.Equal(t, []string{"outer text", "inner text", "handler", "outer delivery", "inner delivery"}, calls)
+}
@@ -0,0 +1,106 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrUnknownPage is returned by PageTokens for the pages it has no access token of.
+var ErrUnknownPage = errors.New("unknown page")
+
+// TokenProvider returns the access tokens of the pages and the Instagram accounts served by the Messenger.
+type TokenProvider interface {
+	// PageToken returns the access token of the page or the Instagram account.
+	// It returns an error wrapping ErrUnknownPage if the page is not served.
+	PageToken(ctx context.Context, pageID int64) (string, error)
+}
+
+// PageTokens is the TokenProvider mapping the page and the Instagram account IDs to the access tokens.
+type PageTokens map[int64]string
+
+// PageToken implements TokenProvider.
+func (p PageTokens) PageToken(_ context.Context, pageID int64) (string, error) {
+	token, ok := p[pageID]
+	if !ok {
+		return "", xerrors.Errorf("page %d: %w", pageID, ErrUnknownPage)
+	}
+
+	return token, nil
+}
+
+// TokenSourceProvider is implemented by the TokenProviders of the pages with the refreshable access tokens.
+// The token source of the page is consulted for each request sent on behalf of the page then.
+type TokenSourceProvider interface {
+	// PageTokenSource returns the token source of the page or the Instagram account.
+	// It returns an error wrapping ErrUnknownPage if the page is not served.
+	PageTokenSource(ctx context.Context, pageID int64) (TokenSource, error)
+}
+
+// TokenSources is the TokenSourceProvider mapping the page and the Instagram account IDs to the token sources.
+type TokenSources map[int64]TokenSource
+
+// PageTokenSource implements TokenSourceProvider.
+func (s TokenSources) PageTokenSource(_ context.Context, pageID int64) (TokenSource, error) {
+	src, ok := s[pageID]
+	if !ok {
+		return nil, xerrors.Errorf("page %d: %w", pageID, ErrUnknownPage)
+	}
+
+	return src, nil
+}
+
+// PageToken implements TokenProvider.
+func (s TokenSources) PageToken(ctx context.Context, pageID int64) (string, error) {
+	src, err := s.PageTokenSource(ctx, pageID)
+	if err != nil {
+		return "", err
+	}
+
+	return src.Token(ctx)
+}
+
+// pageTokenSource returns the token source of the page. The token source of the Messenger is used
+// when there is no TokenProvider or the page is unknown to it.
+func (m *Messenger) pageTokenSource(ctx context.Context, pageID int64) (TokenSource, error) {
+	if m.pages == nil {
+		return m.tokens, nil
+	}
+
+	var (
+		src TokenSource
+		err error
+	)
+	if p, ok := m.pages.(TokenSourceProvider); ok {
+		src, err = p.PageTokenSource(ctx, pageID)
+	} else {
+		var token string
+		token, err = m.pages.PageToken(ctx, pageID)
+		src = StaticTokenSource(token)
+	}
+
+	if errors.Is(err, ErrUnknownPage) && m.tokens != nil {
+		return m.tokens, nil
+	}
+
+	return src, err
+}
+
+// Page returns the Messenger sending the requests on behalf of the page or the Instagram account
+// with its access token. It shares the settings and the Graph API client of m but doesn't handle
+// the webhook events, every outbound method can be called on it.
+func (m *Messenger) Page(ctx context.Context, pageID int64) (*Messenger, error) {
+	src, err := m.pageTokenSource(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Messenger{
+		tokens:         src,
+		pages:          m.pages,
+		sendAPIVersion: m.sendAPIVersion,
+		graph:          m.graph,
+		logger:         m.logger,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessenger_Pages(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		tokens []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens = append(tokens, r.URL.Query().Get("access_token"))
+		mu.Unlock()
+		fmt.Fprint(w, `{"recipient_id": "1", "message_id": "mid"}`)
+	}))
+	defer srv.Close()
+
+	var failures []error
+	m := New(Options{
+		Token:      "default",
+		Pages:      PageTokens{1: "first", 2: "second"},
+		HTTPClient: srv.Client(),
+		GraphURL:   srv.URL,
+		OnError: func(e Event, err error, _ interface{}) {
+			failures = append(failures, err)
+		},
+	})
+	m.HandleMessageE(func(msg Message, r *Response) error {
+		_, err := r.Text("hello", ResponseType, nil, "")
+		return err
+	})
+
+	text := func(pageID int64) Entry {
+		return Entry{ID: pageID, Messaging: []MessageInfo{{Sender: Sender{ID: 10}, Message: &Message{Text: "hi"}}}}
+	}
+	m.dispatch(context.Background(), Receive{Entry: []Entry{text(1), text(2), text(3)}})
+
+	first, err := m.Page(context.Background(), 1)
+	require.NoError(t, err)
+	_, err = first.Send(Recipient{ID: 10}, "hello", ResponseType, nil, "")
+	require.NoError(t, err)
+
+	assert.Empty(t, failures)
+	assert.Equal(t, []string{"first", "second", "default", "first"}, tokens)
+}
+
+func TestMessenger_Pages_UnknownPage(t *testing.T) {
+	t.Parallel()
+
+	var failures []error
+	m := New(Options{
+		Pages: PageTokens{1: "first"},
+		OnError: func(e Event, err error, _ interface{}) {
+			failures = append(failures, err)
+		},
+	})
+
+	called := false
+	m.HandleMessage(func(Message, *Response) { called = true })
+
+	m.dispatch(context.Background(), Receive{Entry: []Entry{{
+		ID:        2,
+		Messaging: []MessageInfo{{Sender: Sender{ID: 10}, Message: &Message{Text: "hi"}}},
+	}}})
+
+	assert.False(t, called)
+	require.Len(t, failures, 1)
+	assert.True(t, errors.Is(failures[0], ErrUnknownPage), failures[0])
+
+	_, err := m.Page(context.Background(), 2)
+	assert.True(t, errors.Is(err, ErrUnknownPage), err)
+}
@@ -5,3 +5,8 @@ type passThreadControl struct {
 	TargetAppID int64     `json:"target_app_id"`
 	Metadata    string    `json:"metadata"`
 }
+
+type threadControl struct {
+	Recipient Recipient `json:"recipient"`
+	Metadata  string    `json:"metadata,omitempty"`
+}
@@ -0,0 +1,107 @@
+package messenger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// MaxPayloadLength is the maximal length of a postback or a quick reply payload.
+const MaxPayloadLength = 1000
+
+// payloadSignatureSize is the size of the truncated HMAC-SHA256 signature of a payload.
+const payloadSignatureSize = 16
+
+var (
+	// ErrPayloadTooLong is returned when the encoded payload exceeds MaxPayloadLength.
+	ErrPayloadTooLong = errors.New("payload is too long")
+	// ErrPayloadMalformed is returned when the payload was not encoded by a PayloadCodec.
+	ErrPayloadMalformed = errors.New("malformed payload")
+	// ErrPayloadVersion is returned when the payload was encoded with another version.
+	ErrPayloadVersion = errors.New("unsupported payload version")
+	// ErrPayloadSignature is returned when the payload signature is missing or invalid.
+	ErrPayloadSignature = errors.New("invalid payload signature")
+)
+
+// PayloadCodec serializes values into compact payload strings for the buttons and the quick replies
+// and decodes them back from the received postbacks and quick replies. The payload is
+// <Prefix>v<Version>.<base64 JSON>[.<base64 signature>].
+type PayloadCodec struct {
+	// Prefix is prepended to every payload, e.g. to route them with HandlePayload(Prefix + "*").
+	Prefix string
+	// Version is written into every payload. Decode rejects the payloads of other versions.
+	Version int
+	// Secret enables HMAC-SHA256 signing of the payloads, so the users cannot forge them.
+	Secret []byte
+}
+
+// Encode returns the payload of the value.
+func (c PayloadCodec) Encode(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	payload := c.header() + base64.RawURLEncoding.EncodeToString(data)
+	if len(c.Secret) > 0 {
+		payload += "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+	}
+
+	if len(payload) > MaxPayloadLength {
+		return "", xerrors.Errorf("%d characters, at most %d allowed: %w", len(payload), MaxPayloadLength, ErrPayloadTooLong)
+	}
+
+	return payload, nil
+}
+
+// Decode stores the value of the payload in the value pointed to by v.
+func (c PayloadCodec) Decode(payload string, v interface{}) error {
+	header := c.header()
+	if !strings.HasPrefix(payload, header) {
+		if strings.HasPrefix(payload, c.Prefix+"v") {
+			return ErrPayloadVersion
+		}
+		return ErrPayloadMalformed
+	}
+
+	parts := strings.Split(payload[len(header):], ".")
+	if len(c.Secret) > 0 {
+		if len(parts) != 2 {
+			return ErrPayloadSignature
+		}
+
+		sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil || !hmac.Equal(sig, c.sign(header+parts[0])) {
+			return ErrPayloadSignature
+		}
+	} else if len(parts) != 1 {
+		return ErrPayloadMalformed
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return xerrors.Errorf("could not decode payload data %q: %w", parts[0], ErrPayloadMalformed)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return NewUnmarshalError(err).WithContent(data)
+	}
+
+	return nil
+}
+
+func (c PayloadCodec) header() string {
+	return c.Prefix + "v" + strconv.Itoa(c.Version) + "."
+}
+
+func (c PayloadCodec) sign(s string) []byte {
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)[:payloadSignatureSize]
+}
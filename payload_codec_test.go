@@ -0,0 +1,47 @@
+package messenger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testOrderPayload struct {
+	OrderID int    `json:"o"`
+	Action  string `json:"a"`
+}
+
+func TestPayloadCodec(t *testing.T) {
+	t.Parallel()
+
+	c := PayloadCodec{Prefix: "ORDER:", Version: 2, Secret: []byte("secret")}
+
+	payload, err := c.Encode(testOrderPayload{OrderID: 42, Action: "cancel"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(payload, "ORDER:v2."))
+
+	var decoded testOrderPayload
+	require.NoError(t, c.Decode(payload, &decoded))
+	assert.Equal(t, testOrderPayload{OrderID: 42, Action: "cancel"}, decoded)
+
+	forged, err := PayloadCodec{Prefix: "ORDER:", Version: 2, Secret: []byte("forged")}.Encode(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, ErrPayloadSignature, c.Decode(forged, &decoded))
+
+	unsigned, err := PayloadCodec{Prefix: "ORDER:", Version: 2}.Encode(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, ErrPayloadSignature, c.Decode(unsigned, &decoded))
+
+	assert.Equal(t, ErrPayloadVersion, PayloadCodec{Prefix: "ORDER:", Version: 3, Secret: c.Secret}.Decode(payload, &decoded))
+	assert.Equal(t, ErrPayloadMalformed, c.Decode("MENU", &decoded))
+}
+
+func TestPayloadCodec_TooLong(t *testing.T) {
+	t.Parallel()
+
+	_, err := PayloadCodec{}.Encode(strings.Repeat("a", MaxPayloadLength))
+	assert.True(t, errors.Is(err, ErrPayloadTooLong))
+}
@@ -0,0 +1,275 @@
+package messenger
+
+// Platform is the platform a webhook event came from. It is the object of the webhook payload.
+type Platform string
+
+const (
+	// MessengerPlatform is the platform of the Facebook page events.
+	MessengerPlatform Platform = "page"
+	// InstagramPlatform is the platform of the Instagram account events.
+	InstagramPlatform Platform = "instagram"
+)
+
+// PlatformHandlers registers handlers which are triggered only by the events of a single platform.
+type PlatformHandlers struct {
+	m        *Messenger
+	platform Platform
+}
+
+// ForPlatform returns PlatformHandlers used to register handlers for the events of the platform
+// on the same Messenger endpoint.
+func (m *Messenger) ForPlatform(p Platform) *PlatformHandlers {
+	return &PlatformHandlers{m: m, platform: p}
+}
+
+// HandleMessage adds a new MessageHandler triggered by the messages of the platform.
+func (h *PlatformHandlers) HandleMessage(f MessageHandler) {
+	h.HandleMessageE(func(msg Message, r *Response) error {
+		f(msg, r)
+		return nil
+	})
+}
+
+// HandleMessageE adds a new MessageHandlerE triggered by the messages of the platform.
+func (h *PlatformHandlers) HandleMessageE(f MessageHandlerE) {
+	h.m.HandleMessageE(func(msg Message, r *Response) error {
+		if msg.Platform != h.platform {
+			return nil
+		}
+		return f(msg, r)
+	})
+}
+
+// HandleEcho adds a new EchoHandler triggered by the message echoes of the platform.
+func (h *PlatformHandlers) HandleEcho(f EchoHandler) {
+	h.HandleEchoE(func(msg Message, r *Response) error {
+		f(msg, r)
+		return nil
+	})
+}
+
+// HandleEchoE adds a new EchoHandlerE triggered by the message echoes of the platform.
+func (h *PlatformHandlers) HandleEchoE(f EchoHandlerE) {
+	h.m.HandleEchoE(func(msg Message, r *Response) error {
+		if msg.Platform != h.platform {
+			return nil
+		}
+		return f(msg, r)
+	})
+}
+
+// HandleDelivery adds a new DeliveryHandler triggered by the delivery receipts of the platform.
+func (h *PlatformHandlers) HandleDelivery(f DeliveryHandler) {
+	h.HandleDeliveryE(func(d Delivery, r *Response) error {
+		f(d, r)
+		return nil
+	})
+}
+
+// HandleDeliveryE adds a new DeliveryHandlerE triggered by the delivery receipts of the platform.
+func (h *PlatformHandlers) HandleDeliveryE(f DeliveryHandlerE) {
+	h.m.HandleDeliveryE(func(d Delivery, r *Response) error {
+		if d.Platform != h.platform {
+			return nil
+		}
+		return f(d, r)
+	})
+}
+
+// HandleRead adds a new ReadHandler triggered by the read receipts of the platform.
+func (h *PlatformHandlers) HandleRead(f ReadHandler) {
+	h.HandleReadE(func(read Read, r *Response) error {
+		f(read, r)
+		return nil
+	})
+}
+
+// HandleReadE adds a new ReadHandlerE triggered by the read receipts of the platform.
+func (h *PlatformHandlers) HandleReadE(f ReadHandlerE) {
+	h.m.HandleReadE(func(read Read, r *Response) error {
+		if read.Platform != h.platform {
+			return nil
+		}
+		return f(read, r)
+	})
+}
+
+// HandlePostBack adds a new PostBackHandler triggered by the postbacks of the platform.
+func (h *PlatformHandlers) HandlePostBack(f PostBackHandler) {
+	h.HandlePostBackE(func(p PostBack, r *Response) error {
+		f(p, r)
+		return nil
+	})
+}
+
+// HandlePostBackE adds a new PostBackHandlerE triggered by the postbacks of the platform.
+func (h *PlatformHandlers) HandlePostBackE(f PostBackHandlerE) {
+	h.m.HandlePostBackE(func(p PostBack, r *Response) error {
+		if p.Platform != h.platform {
+			return nil
+		}
+		return f(p, r)
+	})
+}
+
+// HandleOptIn adds a new OptInHandler triggered by the opt-ins of the platform.
+func (h *PlatformHandlers) HandleOptIn(f OptInHandler) {
+	h.HandleOptInE(func(o OptIn, r *Response) error {
+		f(o, r)
+		return nil
+	})
+}
+
+// HandleOptInE adds a new OptInHandlerE triggered by the opt-ins of the platform.
+func (h *PlatformHandlers) HandleOptInE(f OptInHandlerE) {
+	h.m.HandleOptInE(func(o OptIn, r *Response) error {
+		if o.Platform != h.platform {
+			return nil
+		}
+		return f(o, r)
+	})
+}
+
+// HandleReferral adds a new ReferralHandler triggered by the referrals of the platform.
+func (h *PlatformHandlers) HandleReferral(f ReferralHandler) {
+	h.HandleReferralE(func(ref ReferralMessage, r *Response) error {
+		f(ref, r)
+		return nil
+	})
+}
+
+// HandleReferralE adds a new ReferralHandlerE triggered by the referrals of the platform.
+func (h *PlatformHandlers) HandleReferralE(f ReferralHandlerE) {
+	h.m.HandleReferralE(func(ref ReferralMessage, r *Response) error {
+		if ref.Platform != h.platform {
+			return nil
+		}
+		return f(ref, r)
+	})
+}
+
+// HandleAccountLinking adds a new AccountLinkingHandler triggered by the account linking events of the platform.
+func (h *PlatformHandlers) HandleAccountLinking(f AccountLinkingHandler) {
+	h.HandleAccountLinkingE(func(a AccountLinking, r *Response) error {
+		f(a, r)
+		return nil
+	})
+}
+
+// HandleAccountLinkingE adds a new AccountLinkingHandlerE triggered by the account linking events of the platform.
+func (h *PlatformHandlers) HandleAccountLinkingE(f AccountLinkingHandlerE) {
+	h.m.HandleAccountLinkingE(func(a AccountLinking, r *Response) error {
+		if a.Platform != h.platform {
+			return nil
+		}
+		return f(a, r)
+	})
+}
+
+// HandleReaction adds a new ReactionHandler triggered by the reactions of the platform.
+func (h *PlatformHandlers) HandleReaction(f ReactionHandler) {
+	h.HandleReactionE(func(reaction IGMessageReaction, r *Response) error {
+		f(reaction, r)
+		return nil
+	})
+}
+
+// HandleReactionE adds a new ReactionHandlerE triggered by the reactions of the platform.
+func (h *PlatformHandlers) HandleReactionE(f ReactionHandlerE) {
+	h.m.HandleReactionE(func(reaction IGMessageReaction, r *Response) error {
+		if reaction.Platform != h.platform {
+			return nil
+		}
+		return f(reaction, r)
+	})
+}
+
+// HandlePassThreadControl adds a new PassThreadControlHandler triggered by the events of the platform.
+func (h *PlatformHandlers) HandlePassThreadControl(f PassThreadControlHandler) {
+	h.HandlePassThreadControlE(func(e PassThreadControlEvent, r *Response) error {
+		f(e, r)
+		return nil
+	})
+}
+
+// HandlePassThreadControlE adds a new PassThreadControlHandlerE triggered by the events of the platform.
+func (h *PlatformHandlers) HandlePassThreadControlE(f PassThreadControlHandlerE) {
+	h.m.HandlePassThreadControlE(func(e PassThreadControlEvent, r *Response) error {
+		if e.Platform != h.platform {
+			return nil
+		}
+		return f(e, r)
+	})
+}
+
+// HandleTakeThreadControl adds a new TakeThreadControlHandler triggered by the events of the platform.
+func (h *PlatformHandlers) HandleTakeThreadControl(f TakeThreadControlHandler) {
+	h.HandleTakeThreadControlE(func(e TakeThreadControlEvent, r *Response) error {
+		f(e, r)
+		return nil
+	})
+}
+
+// HandleTakeThreadControlE adds a new TakeThreadControlHandlerE triggered by the events of the platform.
+func (h *PlatformHandlers) HandleTakeThreadControlE(f TakeThreadControlHandlerE) {
+	h.m.HandleTakeThreadControlE(func(e TakeThreadControlEvent, r *Response) error {
+		if e.Platform != h.platform {
+			return nil
+		}
+		return f(e, r)
+	})
+}
+
+// HandleRequestThreadControl adds a new RequestThreadControlHandler triggered by the events of the platform.
+func (h *PlatformHandlers) HandleRequestThreadControl(f RequestThreadControlHandler) {
+	h.HandleRequestThreadControlE(func(e RequestThreadControlEvent, r *Response) error {
+		f(e, r)
+		return nil
+	})
+}
+
+// HandleRequestThreadControlE adds a new RequestThreadControlHandlerE triggered by the events of the platform.
+func (h *PlatformHandlers) HandleRequestThreadControlE(f RequestThreadControlHandlerE) {
+	h.m.HandleRequestThreadControlE(func(e RequestThreadControlEvent, r *Response) error {
+		if e.Platform != h.platform {
+			return nil
+		}
+		return f(e, r)
+	})
+}
+
+// HandleAppRoles adds a new AppRolesHandler triggered by the app roles events of the platform.
+func (h *PlatformHandlers) HandleAppRoles(f AppRolesHandler) {
+	h.HandleAppRolesE(func(e AppRolesEvent, r *Response) error {
+		f(e, r)
+		return nil
+	})
+}
+
+// HandleAppRolesE adds a new AppRolesHandlerE triggered by the app roles events of the platform.
+func (h *PlatformHandlers) HandleAppRolesE(f AppRolesHandlerE) {
+	h.m.HandleAppRolesE(func(e AppRolesEvent, r *Response) error {
+		if e.Platform != h.platform {
+			return nil
+		}
+		return f(e, r)
+	})
+}
+
+// HandleStandby adds a new StandbyHandler triggered by the standby events of the platform.
+func (h *PlatformHandlers) HandleStandby(f StandbyHandler) {
+	h.HandleStandbyE(func(info MessageInfo, r *Response) error {
+		f(info, r)
+		return nil
+	})
+}
+
+// HandleStandbyE adds a new StandbyHandlerE triggered by the standby events of the platform.
+func (h *PlatformHandlers) HandleStandbyE(f StandbyHandlerE) {
+	h.m.HandleStandbyE(func(info MessageInfo, r *Response) error {
+		if info.Platform != h.platform {
+			return nil
+		}
+		return f(info, r)
+	})
+}
@@ -0,0 +1,63 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessenger_PlatformHandlers(t *testing.T) {
+	t.Parallel()
+
+	m := New(Options{})
+
+	var all, instagram []Platform
+	m.HandleMessage(func(msg Message, r *Response) {
+		all = append(all, msg.Platform)
+	})
+	m.ForPlatform(InstagramPlatform).HandleMessage(func(msg Message, r *Response) {
+		instagram = append(instagram, msg.Platform)
+	})
+
+	for object, code := range map[string]string{
+		"page":      `"code": 202`,
+		"instagram": `"code": 202`,
+		"user":      `"code": 422`,
+	} {
+		body := `{"object":"` + object + `","entry":[{"id":"100","messaging":[{"sender":{"id":"1"},"message":{"text":"hi"}}]}]}`
+		w := httptest.NewRecorder()
+		m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		assert.Contains(t, w.Body.String(), code, object)
+	}
+
+	assert.ElementsMatch(t, []Platform{MessengerPlatform, InstagramPlatform}, all)
+	assert.Equal(t, []Platform{InstagramPlatform}, instagram)
+}
+
+func TestPlatformHandlers_HandleMessageE(t *testing.T) {
+	t.Parallel()
+
+	errHandler := errors.New("handler error")
+	var failures []error
+	m := New(Options{
+		OnError: func(e Event, err error, _ interface{}) {
+			failures = append(failures, err)
+		},
+	})
+	m.ForPlatform(InstagramPlatform).HandleMessageE(func(Message, *Response) error {
+		return errHandler
+	})
+
+	for _, p := range []Platform{MessengerPlatform, InstagramPlatform} {
+		m.dispatch(context.Background(), Receive{Object: string(p), Entry: []Entry{{
+			Messaging: []MessageInfo{{Message: &Message{Text: "hi"}}},
+		}}})
+	}
+
+	assert.Equal(t, []error{errHandler}, failures)
+}
@@ -0,0 +1,339 @@
+// Package profilesync keeps the Messenger Profiles of the pages in sync with the declarative
+// YAML or JSON config files:
+//
+//	token_env: SHOP_PAGE_TOKEN
+//	greeting:
+//	  - locale: default
+//	    text: Hello, {{user_first_name}}!
+//	get_started:
+//	  payload: START
+//	whitelisted_domains: []
+//
+// Only the fields present in the config are managed. The empty ones are deleted from the profile
+// and the absent ones are left intact.
+package profilesync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/retailcrm/messenger"
+	"gopkg.in/yaml.v3"
+)
+
+// tokenEnvKey is the config key naming the environment variable with the page access token.
+const tokenEnvKey = "token_env"
+
+// Config is the desired Messenger Profile of a page.
+type Config struct {
+	// Profile is the desired profile.
+	Profile messenger.MessengerProfile
+	// Fields are the fields present in the config, the ones managed by Sync.
+	Fields []messenger.ProfileField
+	// TokenEnv is the environment variable with the page access token.
+	TokenEnv string
+}
+
+// Load reads the config file.
+func Load(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Parse parses the YAML or JSON config.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return cfg, err
+	}
+
+	if env, ok := raw[tokenEnvKey]; ok {
+		s, ok := env.(string)
+		if !ok {
+			return cfg, fmt.Errorf("%s must be a string", tokenEnvKey)
+		}
+		cfg.TokenEnv = s
+		delete(raw, tokenEnvKey)
+	}
+
+	for _, f := range messenger.MessengerProfileFields {
+		if _, ok := raw[string(f)]; ok {
+			cfg.Fields = append(cfg.Fields, f)
+		}
+	}
+
+	if len(cfg.Fields) < len(raw) {
+		for key := range raw {
+			if !isProfileField(key) {
+				return cfg, fmt.Errorf("unknown profile field %q", key)
+			}
+		}
+	}
+
+	// YAML is decoded into the generic values first and then into the typed profile through JSON,
+	// so the json tags of the profile types are the only field names to know.
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return cfg, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg.Profile); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// Action is the change of a profile field.
+type Action string
+
+const (
+	// SetAction sets the field to the desired value.
+	SetAction Action = "set"
+	// DeleteAction deletes the field.
+	DeleteAction Action = "delete"
+)
+
+// Change is the change of a profile field.
+type Change struct {
+	Field   messenger.ProfileField
+	Action  Action
+	Current json.RawMessage
+	Desired json.RawMessage
+}
+
+// Plan is the list of the changes making the current profile the desired one.
+type Plan struct {
+	Changes []Change
+}
+
+// Empty reports whether the profile is in sync already.
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String returns the human readable plan.
+func (p Plan) String() string {
+	if p.Empty() {
+		return "no changes\n"
+	}
+
+	var b strings.Builder
+	for _, c := range p.Changes {
+		switch c.Action {
+		case SetAction:
+			fmt.Fprintf(&b, "~ %s: %s -> %s\n", c.Field, c.Current, c.Desired)
+		case DeleteAction:
+			fmt.Fprintf(&b, "- %s: %s\n", c.Field, c.Current)
+		}
+	}
+
+	return b.String()
+}
+
+// Diff returns the plan making the current profile the desired one. The URLs differing only
+// in the trailing slash or the case of the host are equal, so are the whitelisted domains
+// listed in another order.
+func Diff(current messenger.MessengerProfile, cfg Config) (Plan, error) {
+	var plan Plan
+
+	normCurrent, normDesired := normalize(current), normalize(cfg.Profile)
+
+	for _, f := range cfg.Fields {
+		have, err := fieldJSON(current, f)
+		if err != nil {
+			return plan, err
+		}
+
+		want, err := fieldJSON(cfg.Profile, f)
+		if err != nil {
+			return plan, err
+		}
+
+		same, err := equalField(normCurrent, normDesired, f)
+		if err != nil {
+			return plan, err
+		}
+
+		switch {
+		case same:
+		case want == nil:
+			plan.Changes = append(plan.Changes, Change{Field: f, Action: DeleteAction, Current: have})
+		default:
+			plan.Changes = append(plan.Changes, Change{Field: f, Action: SetAction, Current: have, Desired: want})
+		}
+	}
+
+	return plan, nil
+}
+
+// Apply applies the plan: sets the changed fields with a single request and deletes the others with another one.
+func Apply(ctx context.Context, m *messenger.Messenger, cfg Config, plan Plan) error {
+	var (
+		set     messenger.MessengerProfile
+		deleted []messenger.ProfileField
+	)
+
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case SetAction:
+			copyField(&set, cfg.Profile, c.Field)
+		case DeleteAction:
+			deleted = append(deleted, c.Field)
+		}
+	}
+
+	if len(deleted) < len(plan.Changes) {
+		if err := m.SetMessengerProfile(ctx, set); err != nil {
+			return err
+		}
+	}
+
+	return m.DeleteMessengerProfile(ctx, deleted...)
+}
+
+// Sync fetches the current profile of the page and applies the changes making it the desired one.
+// The dry run only returns the plan.
+func Sync(ctx context.Context, m *messenger.Messenger, cfg Config, dryRun bool) (Plan, error) {
+	if len(cfg.Fields) == 0 {
+		return Plan{}, nil
+	}
+
+	current, err := m.GetMessengerProfile(ctx, cfg.Fields...)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan, err := Diff(current, cfg)
+	if err != nil || dryRun || plan.Empty() {
+		return plan, err
+	}
+
+	return plan, Apply(ctx, m, cfg, plan)
+}
+
+// fieldJSON returns the JSON of the profile field or nil if the field is empty.
+func fieldJSON(p messenger.MessengerProfile, f messenger.ProfileField) (json.RawMessage, error) {
+	var only messenger.MessengerProfile
+	copyField(&only, p, f)
+
+	data, err := json.Marshal(only)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields[string(f)], nil
+}
+
+// equalField reports whether the field of the profiles is the same.
+func equalField(a, b messenger.MessengerProfile, f messenger.ProfileField) (bool, error) {
+	x, err := fieldJSON(a, f)
+	if err != nil {
+		return false, err
+	}
+
+	y, err := fieldJSON(b, f)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(x, y), nil
+}
+
+// normalize returns the copy of the profile with the URLs normalized and the whitelisted domains
+// sorted and deduplicated.
+func normalize(p messenger.MessengerProfile) messenger.MessengerProfile {
+	if len(p.WhitelistedDomains) > 0 {
+		domains := make([]string, 0, len(p.WhitelistedDomains))
+		seen := make(map[string]bool, len(p.WhitelistedDomains))
+		for _, d := range p.WhitelistedDomains {
+			d = normalizeURL(d)
+			if !seen[d] {
+				seen[d] = true
+				domains = append(domains, d)
+			}
+		}
+		sort.Strings(domains)
+		p.WhitelistedDomains = domains
+	}
+
+	p.AccountLinkingURL = normalizeURL(p.AccountLinkingURL)
+
+	if p.HomeURL != nil {
+		home := *p.HomeURL
+		home.URL = normalizeURL(home.URL)
+		p.HomeURL = &home
+	}
+
+	return p
+}
+
+// normalizeURL lowercases the scheme and the host of the URL and strips the root path.
+func normalizeURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "/" {
+		u.Path, u.RawPath = "", ""
+	}
+
+	return u.String()
+}
+
+func isProfileField(key string) bool {
+	for _, f := range messenger.MessengerProfileFields {
+		if key == string(f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// copyField copies the field of the profile.
+func copyField(dst *messenger.MessengerProfile, src messenger.MessengerProfile, f messenger.ProfileField) {
+	switch f {
+	case messenger.GreetingField:
+		dst.Greeting = src.Greeting
+	case messenger.GetStartedField:
+		dst.GetStarted = src.GetStarted
+	case messenger.PersistentMenuField:
+		dst.PersistentMenu = src.PersistentMenu
+	case messenger.IceBreakersField:
+		dst.IceBreakers = src.IceBreakers
+	case messenger.WhitelistedDomainsField:
+		dst.WhitelistedDomains = src.WhitelistedDomains
+	case messenger.AccountLinkingURLField:
+		dst.AccountLinkingURL = src.AccountLinkingURL
+	case messenger.HomeURLField:
+		dst.HomeURL = src.HomeURL
+	}
+}
@@ -0,0 +1,126 @@
+package profilesync
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/retailcrm/messenger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const config = `
+token_env: SHOP_PAGE_TOKEN
+greeting:
+  - locale: default
+    text: Hello!
+get_started:
+  payload: START
+whitelisted_domains: []
+home_url:
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]byte(config))
+	require.NoError(t, err)
+	assert.Equal(t, "SHOP_PAGE_TOKEN", cfg.TokenEnv)
+	assert.Equal(t, []messenger.ProfileField{
+		messenger.GreetingField,
+		messenger.GetStartedField,
+		messenger.WhitelistedDomainsField,
+		messenger.HomeURLField,
+	}, cfg.Fields)
+	assert.Equal(t, messenger.MessengerProfile{
+		Greeting:           []messenger.Greeting{{Locale: messenger.DefaultLocale, Text: "Hello!"}},
+		GetStarted:         &messenger.GetStarted{Payload: "START"},
+		WhitelistedDomains: []string{},
+	}, cfg.Profile)
+
+	json, err := Parse([]byte(`{"get_started": {"payload": "START"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []messenger.ProfileField{messenger.GetStartedField}, json.Fields)
+
+	_, err = Parse([]byte(`greetings: []`))
+	assert.EqualError(t, err, `unknown profile field "greetings"`)
+
+	_, err = Parse([]byte(`get_started: {payload: START, title: Start}`))
+	assert.Error(t, err)
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			assert.Equal(t, "greeting,get_started,whitelisted_domains,home_url", r.URL.Query().Get("fields"))
+			fmt.Fprint(w, `{"data": [{
+				"greeting": [{"locale": "default", "text": "Hi!"}],
+				"get_started": {"payload": "START"},
+				"whitelisted_domains": ["https://example.com"]
+			}]}`)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, r.Method+" "+string(body))
+		fmt.Fprint(w, `{"result": "success"}`)
+	}))
+	defer srv.Close()
+
+	m := messenger.New(messenger.Options{Token: "token", HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	cfg, err := Parse([]byte(config))
+	require.NoError(t, err)
+
+	plan, err := Sync(context.Background(), m, cfg, true)
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+	assert.Equal(t, `~ greeting: [{"locale":"default","text":"Hi!"}] -> [{"locale":"default","text":"Hello!"}]`+"\n"+
+		`- whitelisted_domains: ["https://example.com"]`+"\n", plan.String())
+
+	_, err = Sync(context.Background(), m, cfg, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`POST {"greeting":[{"locale":"default","text":"Hello!"}]}`,
+		`DELETE {"fields":["whitelisted_domains"]}`,
+	}, requests)
+}
+
+func TestSync_Normalize(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		fmt.Fprint(w, `{"data": [{
+			"whitelisted_domains": ["https://shop.example.com/", "https://example.com/"],
+			"home_url": {"url": "https://Example.com/", "webview_height_ratio": "tall"}
+		}]}`)
+	}))
+	defer srv.Close()
+
+	m := messenger.New(messenger.Options{Token: "token", HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	cfg, err := Parse([]byte(`
+whitelisted_domains: [https://example.com, https://shop.example.com, https://example.com]
+home_url: {url: https://example.com, webview_height_ratio: tall}
+`))
+	require.NoError(t, err)
+
+	plan, err := Sync(context.Background(), m, cfg, false)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+}
+
+func TestPlan_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "no changes\n", Plan{}.String())
+}
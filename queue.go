@@ -0,0 +1,103 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShutdown is returned when webhook events are received after Messenger.Shutdown was called.
+var ErrShutdown = errors.New("messenger is shut down")
+
+// eventQueue processes webhook events asynchronously by a pool of workers.
+// Events of the same sender always go to the same worker, so they are processed in order.
+type eventQueue struct {
+	queues  []chan MessageInfo
+	process func(context.Context, MessageInfo)
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	closed  bool
+	// done is closed first on shutdown to release the senders blocked on the full queues
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newEventQueue(workers, size int, process func(context.Context, MessageInfo)) *eventQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &eventQueue{
+		queues:  make([]chan MessageInfo, workers),
+		process: process,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	for i := range q.queues {
+		q.queues[i] = make(chan MessageInfo, size)
+		q.wg.Add(1)
+		go q.work(q.queues[i])
+	}
+
+	return q
+}
+
+func (q *eventQueue) work(queue chan MessageInfo) {
+	defer q.wg.Done()
+
+	for info := range queue {
+		q.process(q.ctx, info)
+	}
+}
+
+// enqueue puts the event into the queue of its sender. It blocks while the queue is full
+// and returns the context error if the context is done first.
+func (q *eventQueue) enqueue(ctx context.Context, info MessageInfo) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrShutdown
+	}
+
+	queue := q.queues[uint64(info.Sender.ID)%uint64(len(q.queues))]
+	select {
+	case queue <- info:
+		return nil
+	case <-q.done:
+		return ErrShutdown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdown stops accepting new events and waits until the queued ones are processed.
+// If the context is done first the context of the running handlers is canceled.
+func (q *eventQueue) shutdown(ctx context.Context) error {
+	q.doneOnce.Do(func() { close(q.done) })
+
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		for _, queue := range q.queues {
+			close(queue)
+		}
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,146 @@
+package messenger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessenger_Workers(t *testing.T) {
+	t.Parallel()
+
+	m := New(Options{Workers: 4, QueueSize: 10})
+
+	var (
+		mu   sync.Mutex
+		seqs = map[int64][]int{}
+	)
+	m.HandleMessage(func(msg Message, r *Response) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		seqs[msg.Sender.ID] = append(seqs[msg.Sender.ID], msg.Seq)
+		mu.Unlock()
+	})
+
+	for seq := 1; seq <= 5; seq++ {
+		var messaging []string
+		for sender := 1; sender <= 3; sender++ {
+			messaging = append(messaging, fmt.Sprintf(
+				`{"sender":{"id":"%d"},"recipient":{"id":"100"},"message":{"seq":%d}}`, sender, seq))
+		}
+
+		body := fmt.Sprintf(`{"object":"page","entry":[{"id":"100","messaging":[%s]}]}`, strings.Join(messaging, ","))
+		w := httptest.NewRecorder()
+		m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	}
+
+	require.NoError(t, m.Shutdown(context.Background()))
+
+	for sender := int64(1); sender <= 3; sender++ {
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, seqs[sender])
+	}
+
+	w := httptest.NewRecorder()
+	body := `{"object":"page","entry":[{"id":"100","messaging":[{"sender":{"id":"1"},"message":{}}]}]}`
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"code": 503`)
+}
+
+func TestEventQueue_Backpressure(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	q := newEventQueue(1, 1, func(context.Context, MessageInfo) {
+		<-release
+	})
+
+	require.NoError(t, q.enqueue(context.Background(), MessageInfo{}))
+	require.NoError(t, q.enqueue(context.Background(), MessageInfo{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, q.enqueue(ctx, MessageInfo{}))
+
+	close(release)
+	require.NoError(t, q.shutdown(context.Background()))
+	assert.Equal(t, ErrShutdown, q.enqueue(context.Background(), MessageInfo{}))
+}
+
+func TestMessenger_Workers_PartialBatch(t *testing.T) {
+	t.Parallel()
+
+	m := New(Options{Workers: 1, QueueSize: 1, DedupStore: NewMemoryDedupStore()})
+
+	release := make(chan struct{})
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+	m.HandleMessage(func(msg Message, r *Response) {
+		<-release
+		mu.Lock()
+		processed = append(processed, msg.Mid)
+		mu.Unlock()
+	})
+
+	var messaging []string
+	for seq := 1; seq <= 4; seq++ {
+		messaging = append(messaging, fmt.Sprintf(
+			`{"sender":{"id":"1"},"recipient":{"id":"100"},"message":{"mid":"m%d","seq":%d}}`, seq, seq))
+	}
+	body := fmt.Sprintf(`{"object":"page","entry":[{"id":"100","messaging":[%s]}]}`, strings.Join(messaging, ","))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)).WithContext(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// Facebook redelivers the batch, the events queued before are deduplicated
+	close(release)
+	w = httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.Equal(t, []string{"m1", "m2", "m3", "m4"}, processed)
+}
+
+func TestEventQueue_ShutdownBlockedSender(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	q := newEventQueue(1, 1, func(context.Context, MessageInfo) {
+		<-release
+	})
+	defer close(release)
+
+	require.NoError(t, q.enqueue(context.Background(), MessageInfo{}))
+	require.NoError(t, q.enqueue(context.Background(), MessageInfo{}))
+
+	blocked := make(chan error)
+	go func() {
+		blocked <- q.enqueue(context.Background(), MessageInfo{})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, q.shutdown(ctx))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, ErrShutdown, <-blocked)
+}
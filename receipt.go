@@ -0,0 +1,170 @@
+package messenger
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// MaxReceiptElements is the maximal number of the elements of a receipt template.
+const MaxReceiptElements = 100
+
+var currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Receipt is the payload of a receipt template confirming an order.
+// The amounts are exact decimals in the Currency.
+type Receipt struct {
+	// RecipientName is the name of the customer. Required.
+	RecipientName string `json:"recipient_name"`
+	// OrderNumber is the unique number of the order. Required.
+	OrderNumber string `json:"order_number"`
+	// Currency is the ISO 4217 code of the currency, e.g. "USD". Required.
+	Currency string `json:"currency"`
+	// PaymentMethod is shown to the customer, e.g. "Visa 2345". Required.
+	PaymentMethod string `json:"payment_method"`
+	// OrderURL is the URL of the order.
+	OrderURL string `json:"order_url,omitempty"`
+	// Timestamp is the Unix time of the order in seconds.
+	Timestamp int64 `json:"timestamp,omitempty,string"`
+	// Sharable enables the native share button.
+	Sharable bool `json:"sharable,omitempty"`
+	// Address is the shipping address.
+	Address *ReceiptAddress `json:"address,omitempty"`
+	// Summary is the payment summary. TotalCost is required.
+	Summary ReceiptSummary `json:"summary"`
+	// Adjustments are the discounts of the order.
+	Adjustments []ReceiptAdjustment `json:"adjustments,omitempty"`
+	// Elements are the ordered items.
+	Elements []ReceiptElement `json:"elements,omitempty"`
+}
+
+// ReceiptAddress is the shipping address of a receipt.
+type ReceiptAddress struct {
+	Street1    string `json:"street_1"`
+	Street2    string `json:"street_2,omitempty"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code"`
+	State      string `json:"state"`
+	Country    string `json:"country"`
+}
+
+// ReceiptSummary is the payment summary of a receipt.
+type ReceiptSummary struct {
+	Subtotal     Decimal `json:"subtotal,omitempty"`
+	ShippingCost Decimal `json:"shipping_cost,omitempty"`
+	TotalTax     Decimal `json:"total_tax,omitempty"`
+	TotalCost    Decimal `json:"total_cost"`
+}
+
+// ReceiptAdjustment is a discount of a receipt.
+type ReceiptAdjustment struct {
+	Name   string  `json:"name"`
+	Amount Decimal `json:"amount"`
+}
+
+// ReceiptElement is an ordered item of a receipt.
+type ReceiptElement struct {
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle,omitempty"`
+	Quantity int     `json:"quantity,omitempty"`
+	Price    Decimal `json:"price"`
+	Currency string  `json:"currency,omitempty"`
+	ImageURL string  `json:"image_url,omitempty"`
+}
+
+// TemplateType implements Template.
+func (r *Receipt) TemplateType() string {
+	return "receipt"
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *Receipt) MarshalJSON() ([]byte, error) {
+	type receipt Receipt
+	return json.Marshal(struct {
+		TemplateType string `json:"template_type"`
+		*receipt
+	}{r.TemplateType(), (*receipt)(r)})
+}
+
+// Validate checks the required fields and the amounts of the receipt.
+// It returns a *ValidationError listing every violation.
+func (r *Receipt) Validate() error {
+	v := &validator{subject: "receipt"}
+	r.validate(v, "receipt")
+	return v.err()
+}
+
+func (r *Receipt) validate(v *validator, field string) {
+	v.required(field+".recipient_name", r.RecipientName)
+	v.required(field+".order_number", r.OrderNumber)
+	v.required(field+".payment_method", r.PaymentMethod)
+	v.currency(field+".currency", r.Currency, true)
+
+	if a := r.Address; a != nil {
+		v.required(field+".address.street_1", a.Street1)
+		v.required(field+".address.city", a.City)
+		v.required(field+".address.postal_code", a.PostalCode)
+		v.required(field+".address.state", a.State)
+		v.required(field+".address.country", a.Country)
+	}
+
+	v.decimal(field+".summary.subtotal", r.Summary.Subtotal, false)
+	v.decimal(field+".summary.shipping_cost", r.Summary.ShippingCost, false)
+	v.decimal(field+".summary.total_tax", r.Summary.TotalTax, false)
+	v.decimal(field+".summary.total_cost", r.Summary.TotalCost, true)
+
+	for i, a := range r.Adjustments {
+		af := fmt.Sprintf("%s.adjustments[%d]", field, i)
+		v.required(af+".name", a.Name)
+		v.decimal(af+".amount", a.Amount, true)
+	}
+
+	if len(r.Elements) > MaxReceiptElements {
+		v.add(field+".elements", "%d elements, at most %d allowed", len(r.Elements), MaxReceiptElements)
+	}
+
+	for i, e := range r.Elements {
+		ef := fmt.Sprintf("%s.elements[%d]", field, i)
+		v.required(ef+".title", e.Title)
+		v.decimal(ef+".price", e.Price, true)
+		v.currency(ef+".currency", e.Currency, false)
+		if e.Quantity < 0 {
+			v.add(ef+".quantity", "must not be negative")
+		}
+	}
+}
+
+// decimal checks the value is a decimal number.
+func (v *validator) decimal(field string, d Decimal, required bool) {
+	switch {
+	case d == "":
+		if required {
+			v.add(field, "is required")
+		}
+	case !d.Valid():
+		v.add(field, "%q is not a decimal number", string(d))
+	}
+}
+
+// currency checks the value is an ISO 4217 currency code.
+func (v *validator) currency(field, value string, required bool) {
+	switch {
+	case value == "":
+		if required {
+			v.add(field, "is required")
+		}
+	case !currencyRegexp.MatchString(value):
+		v.add(field, "%q is not an ISO 4217 currency code", value)
+	}
+}
+
+// ReceiptTemplate sends the receipt template confirming the order.
+// Nothing is sent when the receipt is invalid, the *ValidationError lists every violation then.
+func (r *Response) ReceiptTemplate(receipt Receipt, opts ...SendOption) (QueryResponse, error) {
+	return r.Send(r.Context(), MessageData{Attachment: NewTemplate(&receipt)}, opts...)
+}
+
+// ReceiptTemplate sends the receipt template to the recipient.
+func (m *Messenger) ReceiptTemplate(to Recipient, receipt Receipt, opts ...SendOption) (QueryResponse, error) {
+	return m.newResponse(to).ReceiptTemplate(receipt, opts...)
+}
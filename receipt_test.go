@@ -0,0 +1,125 @@
+package messenger
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDecimal(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, Decimal("19.99"), NewDecimal(1999, 2))
+	assert.Equal(t, Decimal("0.05"), NewDecimal(5, 2))
+	assert.Equal(t, Decimal("-0.5"), NewDecimal(-5, 1))
+	assert.Equal(t, Decimal("42"), NewDecimal(42, 0))
+
+	d, err := ParseDecimal("100.10")
+	require.NoError(t, err)
+	assert.Equal(t, Decimal("100.10"), d)
+
+	_, err = ParseDecimal("1e3")
+	assert.EqualError(t, err, `invalid decimal "1e3"`)
+
+	var parsed struct{ Price Decimal }
+	require.NoError(t, json.Unmarshal([]byte(`{"Price": 0.1}`), &parsed))
+	assert.Equal(t, Decimal("0.1"), parsed.Price)
+
+	var summary ReceiptSummary
+	require.NoError(t, json.Unmarshal([]byte(`{"subtotal": null, "total_tax": "", "total_cost": "5.50"}`), &summary))
+	assert.Equal(t, ReceiptSummary{TotalCost: "5.50"}, summary)
+}
+
+func TestResponse_ReceiptTemplate(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	m, closeServer := newSendServer(t, &bodies)
+	defer closeServer()
+
+	_, err := m.Response(154).ReceiptTemplate(Receipt{
+		RecipientName: "Stephane Crozatier",
+		OrderNumber:   "12345678902",
+		Currency:      "USD",
+		PaymentMethod: "Visa 2345",
+		Timestamp:     1428444852,
+		Address: &ReceiptAddress{
+			Street1:    "1 Hacker Way",
+			City:       "Menlo Park",
+			PostalCode: "94025",
+			State:      "CA",
+			Country:    "US",
+		},
+		Summary: ReceiptSummary{
+			Subtotal:  NewDecimal(7500, 2),
+			TotalCost: "56.14",
+		},
+		Adjustments: []ReceiptAdjustment{{Name: "Coupon", Amount: "0.10"}},
+		Elements: []ReceiptElement{
+			{Title: "Classic White T-Shirt", Quantity: 2, Price: "50", Currency: "USD"},
+		},
+	}, WithTag("POST_PURCHASE_UPDATE"))
+	require.NoError(t, err)
+
+	require.Len(t, bodies, 1)
+	assert.JSONEq(t, `{
+		"messaging_type": "MESSAGE_TAG",
+		"tag": "POST_PURCHASE_UPDATE",
+		"recipient": {"id": "154"},
+		"message": {"attachment": {"type": "template", "payload": {
+			"template_type": "receipt",
+			"recipient_name": "Stephane Crozatier",
+			"order_number": "12345678902",
+			"currency": "USD",
+			"payment_method": "Visa 2345",
+			"timestamp": "1428444852",
+			"address": {
+				"street_1": "1 Hacker Way",
+				"city": "Menlo Park",
+				"postal_code": "94025",
+				"state": "CA",
+				"country": "US"
+			},
+			"summary": {"subtotal": 75.00, "total_cost": 56.14},
+			"adjustments": [{"name": "Coupon", "amount": 0.10}],
+			"elements": [{"title": "Classic White T-Shirt", "quantity": 2, "price": 50, "currency": "USD"}]
+		}}}
+	}`, bodies[0])
+	assert.Contains(t, bodies[0], `"subtotal":75.00`)
+}
+
+func TestReceipt_Validate(t *testing.T) {
+	t.Parallel()
+
+	r := Receipt{
+		Currency: "usd",
+		Address:  &ReceiptAddress{Street1: "1 Hacker Way"},
+		Summary:  ReceiptSummary{Subtotal: "1,5"},
+		Elements: []ReceiptElement{{Price: "10"}},
+	}
+
+	err := r.Validate()
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr), err)
+	assert.Equal(t, []Violation{
+		{Field: "receipt.recipient_name", Message: "is required"},
+		{Field: "receipt.order_number", Message: "is required"},
+		{Field: "receipt.payment_method", Message: "is required"},
+		{Field: "receipt.currency", Message: `"usd" is not an ISO 4217 currency code`},
+		{Field: "receipt.address.city", Message: "is required"},
+		{Field: "receipt.address.postal_code", Message: "is required"},
+		{Field: "receipt.address.state", Message: "is required"},
+		{Field: "receipt.address.country", Message: "is required"},
+		{Field: "receipt.summary.subtotal", Message: `"1,5" is not a decimal number`},
+		{Field: "receipt.summary.total_cost", Message: "is required"},
+		{Field: "receipt.elements[0].title", Message: "is required"},
+	}, verr.Violations)
+
+	_, err = (&Response{}).ReceiptTemplate(r)
+	require.True(t, errors.As(err, &verr), err)
+	assert.Equal(t, "message.attachment.payload.recipient_name", verr.Violations[0].Field)
+}
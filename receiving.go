@@ -4,13 +4,34 @@ import "time"
 
 // Receive is the format in which webhook events are sent.
 type Receive struct {
-	// Object should always be `page`. (I don't quite understand why)
+	// Object is `page` for the Messenger events and `instagram` for the Instagram ones.
 	Object string `json:"object"`
 	// Entry is all of the different messenger types which were
 	// sent in this event.
 	Entry []Entry `json:"entry"`
 }
 
+// events returns the events of all entries with the platform and the page they came from.
+// The standby events follow the regular ones.
+func (r Receive) events() []MessageInfo {
+	var events []MessageInfo
+	for _, entry := range r.Entry {
+		for _, info := range entry.Messaging {
+			info.Platform = Platform(r.Object)
+			info.PageID = entry.ID
+			events = append(events, info)
+		}
+		for _, info := range entry.Standby {
+			info.Platform = Platform(r.Object)
+			info.PageID = entry.ID
+			info.Standby = true
+			events = append(events, info)
+		}
+	}
+
+	return events
+}
+
 // Entry is a batch of events which were sent in this webhook trigger.
 type Entry struct {
 	// ID is the ID of the batch.
@@ -19,6 +40,8 @@ type Entry struct {
 	Time int64 `json:"time"`
 	// Messaging is the events that were sent in this Entry
 	Messaging []MessageInfo `json:"messaging"`
+	// Standby is the events that were sent in this Entry while the app is not the thread owner.
+	Standby []MessageInfo `json:"standby"`
 }
 
 // MessageInfo is an event that is fired by the webhook.
@@ -47,6 +70,21 @@ type MessageInfo struct {
 	ReferralMessage *ReferralMessage `json:"referral"`
 
 	AccountLinking *AccountLinking `json:"account_linking"`
+
+	PassThreadControl *PassThreadControlEvent `json:"pass_thread_control"`
+
+	TakeThreadControl *TakeThreadControlEvent `json:"take_thread_control"`
+
+	RequestThreadControl *RequestThreadControlEvent `json:"request_thread_control"`
+
+	AppRoles AppRoles `json:"app_roles"`
+
+	// Platform is the platform the event came from.
+	Platform Platform `json:"-"`
+	// PageID is the ID of the page or the Instagram account the event was sent to.
+	PageID int64 `json:"-"`
+	// Standby is true if the event was received while the app is not the thread owner.
+	Standby bool `json:"-"`
 }
 
 type OptIn struct {
@@ -56,6 +94,8 @@ type OptIn struct {
 	Recipient Recipient `json:"-"`
 	// Time is when the message was sent.
 	Time time.Time `json:"-"`
+	// Platform is the platform the message was sent on.
+	Platform Platform `json:"-"`
 	// Ref is the reference as given
 	Ref string `json:"ref"`
 }
@@ -70,6 +110,8 @@ type ReferralMessage struct {
 	Recipient Recipient `json:"-"`
 	// Time is when the message was sent.
 	Time time.Time `json:"-"`
+	// Platform is the platform the message was sent on.
+	Platform Platform `json:"-"`
 }
 
 // Referral represents referral info.
@@ -0,0 +1,51 @@
+package messenger
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is reported to Options.OnError when a handler or a middleware panics.
+type PanicError struct {
+	// Value is the recovered value.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+// PanicError implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panic: %v", e.Value)
+}
+
+// invoke calls the handler recovering from a panic. The failure is reported.
+func (m *Messenger) invoke(e Event, f func() error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &PanicError{Value: rec, Stack: debug.Stack()}
+			m.reportError(e, err, rec)
+		}
+	}()
+
+	if err = f(); err != nil {
+		m.reportError(e, err, nil)
+	}
+
+	return err
+}
+
+// reportError passes the failure of the event processing to Options.OnError or logs it.
+func (m *Messenger) reportError(e Event, err error, recovered interface{}) {
+	if m.onError != nil {
+		m.onError(e, err, recovered)
+		return
+	}
+
+	m.log().Error("webhook handler failed",
+		"page_id", e.Info.PageID,
+		"sender_id", e.Info.Sender.ID,
+		"platform", e.Info.Platform,
+		"action", e.Action,
+		"error", err,
+	)
+}
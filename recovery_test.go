@@ -0,0 +1,104 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessenger_OnError(t *testing.T) {
+	t.Parallel()
+
+	type failure struct {
+		action    Action
+		err       error
+		recovered interface{}
+	}
+
+	var failures []failure
+	m := New(Options{
+		OnError: func(e Event, err error, recovered interface{}) {
+			failures = append(failures, failure{e.Action, err, recovered})
+		},
+	})
+
+	errHandler := errors.New("handler error")
+	var calls int
+	m.HandleMessage(func(Message, *Response) {
+		panic("boom")
+	})
+	m.HandleMessageE(func(Message, *Response) error {
+		calls++
+		return errHandler
+	})
+	m.HandleMessage(func(Message, *Response) {
+		calls++
+	})
+
+	var chainErr error
+	m.Use(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, e Event) error {
+			chainErr = next(ctx, e)
+			return chainErr
+		}
+	})
+
+	m.dispatch(context.Background(), Receive{Entry: []Entry{{Messaging: []MessageInfo{{Message: &Message{}}}}}})
+
+	assert.Equal(t, 2, calls)
+	require.Len(t, failures, 2)
+	assert.Equal(t, TextAction, failures[0].action)
+	assert.Equal(t, "boom", failures[0].recovered)
+	panicErr, ok := failures[0].err.(*PanicError)
+	require.True(t, ok)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.Equal(t, errHandler, failures[1].err)
+	assert.Nil(t, failures[1].recovered)
+	assert.Equal(t, panicErr, chainErr)
+}
+
+func TestMessenger_MiddlewarePanic(t *testing.T) {
+	t.Parallel()
+
+	var recovered interface{}
+	m := New(Options{
+		OnError: func(e Event, err error, rec interface{}) {
+			recovered = rec
+		},
+	})
+	m.Use(func(next EventHandler) EventHandler {
+		return func(ctx context.Context, e Event) error {
+			panic("middleware")
+		}
+	})
+
+	m.dispatch(context.Background(), Receive{Entry: []Entry{{Messaging: []MessageInfo{{Message: &Message{}}}}}})
+	assert.Equal(t, "middleware", recovered)
+}
+
+type panicTokenProvider struct{}
+
+func (panicTokenProvider) PageToken(context.Context, int64) (string, error) {
+	panic("provider boom")
+}
+
+func TestMessenger_TokenProviderPanic(t *testing.T) {
+	t.Parallel()
+
+	var recovered []interface{}
+	m := New(Options{
+		Pages: panicTokenProvider{},
+		OnError: func(e Event, err error, rec interface{}) {
+			recovered = append(recovered, rec)
+		},
+	})
+
+	assert.NotPanics(t, func() {
+		m.dispatch(context.Background(), Receive{Entry: []Entry{{Messaging: []MessageInfo{{Message: &Message{}}}}}})
+	})
+	assert.Equal(t, []interface{}{"provider boom"}, recovered)
+}
@@ -2,6 +2,7 @@ package messenger
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"image"
@@ -9,7 +10,6 @@ import (
 	"io"
 	"io/ioutil"
 	"mime/multipart"
-	"net/http"
 	"net/textproto"
 	"strings"
 )
@@ -24,9 +24,9 @@ const (
 	// DefaultSendAPIVersion is a default Send API version
 	DefaultSendAPIVersion = "v2.11"
 	// SendMessageURL is API endpoint for sending messages.
-	SendMessageURL = "https://graph.facebook.com/%s/me/messages"
+	SendMessageURL = DefaultGraphURL + sendMessagePath
 	// ThreadControlURL is the API endpoint for passing thread control.
-	ThreadControlURL = "https://graph.facebook.com/%s/me/pass_thread_control"
+	ThreadControlURL = DefaultGraphURL + threadControlPath
 	// InboxPageID is managed by facebook for secondary pass to inbox features: https://developers.facebook.com/docs/messenger-platform/handover-protocol/pass-thread-control
 	InboxPageID = 263902037430900
 
@@ -115,13 +115,53 @@ func getFacebookQueryResponse(r io.Reader) (QueryResponse, error) {
 // Response is used for responding to events with messages.
 type Response struct {
 	token          string
+	tokens         TokenSource
 	to             Recipient
 	sendAPIVersion string
+	graph          *graphClient
+	ctx            context.Context
 }
 
 // SetToken is for using DispatchMessage from outside.
 func (r *Response) SetToken(token string) {
 	r.token = token
+	r.tokens = nil
+}
+
+// SetTokenSource makes the Response consult the source for the access token of each request.
+func (r *Response) SetTokenSource(src TokenSource) {
+	r.tokens = src
+}
+
+// tokenSource returns the source of the access tokens of the Response.
+func (r *Response) tokenSource() TokenSource {
+	if r.tokens != nil {
+		return r.tokens
+	}
+
+	return StaticTokenSource(r.token)
+}
+
+// Context returns the context every request of the Response is bound to.
+// For the Responses passed to handlers it is the context of the webhook request.
+func (r *Response) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+
+	return context.Background()
+}
+
+// WithContext returns a shallow copy of the Response bound to the context.
+func (r *Response) WithContext(ctx context.Context) *Response {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	r2 := *r
+	r2.ctx = ctx
+
+	return &r2
 }
 
 // Text sends a textual message.
@@ -147,24 +187,8 @@ func (r *Response) TextWithReplies(
 	metadata string,
 	tags ...string,
 ) (QueryResponse, error) {
-	var tag string
-	if len(tags) > 0 {
-		tag = tags[0]
-	}
-
-	m := SendMessage{
-		MessagingType: messagingType,
-		Recipient:     r.to,
-		ThreadControl: threadControl,
-		Message: MessageData{
-			Text:         message,
-			Attachment:   nil,
-			QuickReplies: replies,
-			Metadata:     metadata,
-		},
-		Tag: tag,
-	}
-	return r.DispatchMessage(&m)
+	msg := MessageData{Text: message, QuickReplies: replies}
+	return r.Send(r.Context(), msg, legacyOptions(messagingType, threadControl, metadata, tags)...)
 }
 
 // AttachmentWithReplies sends a attachment message with some replies.
@@ -176,23 +200,8 @@ func (r *Response) AttachmentWithReplies(
 	metadata string,
 	tags ...string,
 ) (QueryResponse, error) {
-	var tag string
-	if len(tags) > 0 {
-		tag = tags[0]
-	}
-
-	m := SendMessage{
-		MessagingType: messagingType,
-		Recipient:     r.to,
-		ThreadControl: control,
-		Message: MessageData{
-			Attachment:   attachment,
-			QuickReplies: replies,
-			Metadata:     metadata,
-		},
-		Tag: tag,
-	}
-	return r.DispatchMessage(&m)
+	msg := MessageData{Attachment: attachment, QuickReplies: replies}
+	return r.Send(r.Context(), msg, legacyOptions(messagingType, control, metadata, tags)...)
 }
 
 // Image sends an image.
@@ -217,27 +226,8 @@ func (r *Response) Attachment(
 	metadata string,
 	tags ...string,
 ) (QueryResponse, error) {
-	var tag string
-	if len(tags) > 0 {
-		tag = tags[0]
-	}
-
-	m := SendStructuredMessage{
-		MessagingType: messagingType,
-		ThreadControl: control,
-		Recipient:     r.to,
-		Message: StructuredMessageData{
-			Metadata: metadata,
-			Attachment: StructuredMessageAttachment{
-				Type: dataType,
-				Payload: StructuredMessagePayload{
-					Url: url,
-				},
-			},
-		},
-		Tag: tag,
-	}
-	return r.DispatchMessage(&m)
+	msg := MessageData{Attachment: NewAttachment(dataType, url)}
+	return r.Send(r.Context(), msg, legacyOptions(messagingType, control, metadata, tags)...)
 }
 
 // copied from multipart package.
@@ -283,17 +273,14 @@ func (r *Response) AttachmentData(
 	multipartWriter.WriteField("recipient", fmt.Sprintf(`{"id":"%v"}`, r.to.ID))
 	multipartWriter.WriteField("message", fmt.Sprintf(`{"attachment":{"type":"%v", "payload":{}}}`, dataType))
 
-	req, err := http.NewRequest("POST", fmt.Sprintf(SendMessageURL, r.sendAPIVersion), &body)
+	req, err := r.graph.newRequest(r.Context(), "POST", fmt.Sprintf(sendMessagePath, r.sendAPIVersion), &body)
 	if err != nil {
 		return qr, err
 	}
 
-	req.URL.RawQuery = "access_token=" + r.token
-
 	req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.graph.send(req, r.tokenSource())
 	if err != nil {
 		return qr, err
 	}
@@ -303,31 +290,15 @@ func (r *Response) AttachmentData(
 }
 
 // ButtonTemplate sends a message with the main contents being button elements.
+// Use Send with NewButtonTemplate to set the thread control.
 func (r *Response) ButtonTemplate(text string, buttons *[]StructuredMessageButton, messagingType MessagingType, metadata string, tags ...string) (QueryResponse, error) {
-	var tag string
-	if len(tags) > 0 {
-		tag = tags[0]
+	var b []StructuredMessageButton
+	if buttons != nil {
+		b = *buttons
 	}
 
-	m := SendStructuredMessage{
-		MessagingType: messagingType,
-		Recipient:     r.to,
-		Message: StructuredMessageData{
-			Metadata: metadata,
-			Attachment: StructuredMessageAttachment{
-				Type: "template",
-				Payload: StructuredMessagePayload{
-					TemplateType: "button",
-					Text:         text,
-					Buttons:      buttons,
-					Elements:     nil,
-				},
-			},
-		},
-		Tag: tag,
-	}
-
-	return r.DispatchMessage(&m)
+	msg := MessageData{Attachment: NewButtonTemplate(text, b...)}
+	return r.Send(r.Context(), msg, legacyOptions(messagingType, nil, metadata, tags)...)
 }
 
 // GenericTemplate is a message which allows for structural elements to be sent.
@@ -338,55 +309,25 @@ func (r *Response) GenericTemplate(
 	metadata string,
 	tags ...string,
 ) (QueryResponse, error) {
-	var tag string
-	if len(tags) > 0 {
-		tag = tags[0]
+	var e []StructuredMessageElement
+	if elements != nil {
+		e = *elements
 	}
 
-	m := SendStructuredMessage{
-		MessagingType: messagingType,
-		Recipient:     r.to,
-		ThreadControl: control,
-		Message: StructuredMessageData{
-			Metadata: metadata,
-			Attachment: StructuredMessageAttachment{
-				Type: "template",
-				Payload: StructuredMessagePayload{
-					TemplateType: "generic",
-					Buttons:      nil,
-					Elements:     elements,
-				},
-			},
-		},
-		Tag: tag,
-	}
-	return r.DispatchMessage(&m)
+	msg := MessageData{Attachment: NewGenericTemplate(e...)}
+	return r.Send(r.Context(), msg, legacyOptions(messagingType, control, metadata, tags)...)
 }
 
 // ListTemplate sends a list of elements.
+// Use Send with NewListTemplate to set the thread control and the metadata.
 func (r *Response) ListTemplate(elements *[]StructuredMessageElement, messagingType MessagingType, tags ...string) (QueryResponse, error) {
-	var tag string
-	if len(tags) > 0 {
-		tag = tags[0]
+	var e []StructuredMessageElement
+	if elements != nil {
+		e = *elements
 	}
 
-	m := SendStructuredMessage{
-		MessagingType: messagingType,
-		Recipient:     r.to,
-		Message: StructuredMessageData{
-			Attachment: StructuredMessageAttachment{
-				Type: "template",
-				Payload: StructuredMessagePayload{
-					TopElementStyle: "compact",
-					TemplateType:    "list",
-					Buttons:         nil,
-					Elements:        elements,
-				},
-			},
-		},
-		Tag: tag,
-	}
-	return r.DispatchMessage(&m)
+	msg := MessageData{Attachment: NewListTemplate(e...)}
+	return r.Send(r.Context(), msg, legacyOptions(messagingType, nil, "", tags)...)
 }
 
 // SenderAction sends an info about sender action.
@@ -415,21 +356,24 @@ func (r *Response) InstagramReaction(mid string, action ReactionAction, reaction
 
 // DispatchMessage posts the message to messenger, return the error if there's any.
 func (r *Response) DispatchMessage(m interface{}) (QueryResponse, error) {
+	return r.DispatchMessageCtx(r.Context(), m)
+}
+
+// DispatchMessageCtx is like DispatchMessage but the request is bound to the context.
+func (r *Response) DispatchMessageCtx(ctx context.Context, m interface{}) (QueryResponse, error) {
 	var res QueryResponse
 	data, err := json.Marshal(m)
 	if err != nil {
 		return res, err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf(SendMessageURL, r.sendAPIVersion), bytes.NewBuffer(data))
+	req, err := r.graph.newRequest(ctx, "POST", fmt.Sprintf(sendMessagePath, r.sendAPIVersion), bytes.NewBuffer(data))
 	if err != nil {
 		return res, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.URL.RawQuery = "access_token=" + r.token
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := r.graph.send(req, r.tokenSource())
 	if err != nil {
 		return res, err
 	}
@@ -442,26 +386,84 @@ func (r *Response) DispatchMessage(m interface{}) (QueryResponse, error) {
 // PassThreadToInbox Uses Messenger Handover Protocol for live inbox
 // https://developers.facebook.com/docs/messenger-platform/handover-protocol/#inbox
 func (r *Response) PassThreadToInbox() error {
+	return r.PassThreadToInboxCtx(r.Context())
+}
+
+// PassThreadToInboxCtx is like PassThreadToInbox but the request is bound to the context.
+func (r *Response) PassThreadToInboxCtx(ctx context.Context) error {
 	p := passThreadControl{
 		Recipient:   r.to,
 		TargetAppID: InboxPageID,
 		Metadata:    "Passing to inbox secondary app",
 	}
 
+	return r.sendThreadControl(ctx, threadControlPath, p)
+}
+
+// PassThreadControl passes thread control to the app using Messenger Handover Protocol
+// https://developers.facebook.com/docs/messenger-platform/handover-protocol/pass-thread-control
+func (r *Response) PassThreadControl(targetAppID int64, metadata string) error {
+	return r.PassThreadControlCtx(r.Context(), targetAppID, metadata)
+}
+
+// PassThreadControlCtx is like PassThreadControl but the request is bound to the context.
+func (r *Response) PassThreadControlCtx(ctx context.Context, targetAppID int64, metadata string) error {
+	p := passThreadControl{
+		Recipient:   r.to,
+		TargetAppID: targetAppID,
+		Metadata:    metadata,
+	}
+
+	return r.sendThreadControl(ctx, threadControlPath, p)
+}
+
+// TakeThreadControl takes thread control from the app which owns it. Only the primary receiver may take it
+// https://developers.facebook.com/docs/messenger-platform/handover-protocol/take-thread-control
+func (r *Response) TakeThreadControl(metadata string) error {
+	return r.TakeThreadControlCtx(r.Context(), metadata)
+}
+
+// TakeThreadControlCtx is like TakeThreadControl but the request is bound to the context.
+func (r *Response) TakeThreadControlCtx(ctx context.Context, metadata string) error {
+	return r.sendThreadControl(ctx, takeThreadPath, threadControl{Recipient: r.to, Metadata: metadata})
+}
+
+// RequestThreadControl asks the primary receiver to pass thread control to the app
+// https://developers.facebook.com/docs/messenger-platform/handover-protocol/request-thread-control
+func (r *Response) RequestThreadControl(metadata string) error {
+	return r.RequestThreadControlCtx(r.Context(), metadata)
+}
+
+// RequestThreadControlCtx is like RequestThreadControl but the request is bound to the context.
+func (r *Response) RequestThreadControlCtx(ctx context.Context, metadata string) error {
+	return r.sendThreadControl(ctx, requestThreadPath, threadControl{Recipient: r.to, Metadata: metadata})
+}
+
+// ReleaseThreadControl releases thread control back to the primary receiver
+// https://developers.facebook.com/docs/messenger-platform/handover-protocol/release-thread-control
+func (r *Response) ReleaseThreadControl(metadata string) error {
+	return r.ReleaseThreadControlCtx(r.Context(), metadata)
+}
+
+// ReleaseThreadControlCtx is like ReleaseThreadControl but the request is bound to the context.
+func (r *Response) ReleaseThreadControlCtx(ctx context.Context, metadata string) error {
+	return r.sendThreadControl(ctx, releaseThreadPath, threadControl{Recipient: r.to, Metadata: metadata})
+}
+
+// sendThreadControl posts the handover protocol request to the path.
+func (r *Response) sendThreadControl(ctx context.Context, path string, p interface{}) error {
 	data, err := json.Marshal(p)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf(ThreadControlURL, r.sendAPIVersion), bytes.NewBuffer(data))
+	req, err := r.graph.newRequest(ctx, "POST", fmt.Sprintf(path, r.sendAPIVersion), bytes.NewBuffer(data))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.URL.RawQuery = "access_token=" + r.token
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := r.graph.send(req, r.tokenSource())
 	if err != nil {
 		return err
 	}
@@ -472,11 +474,13 @@ func (r *Response) PassThreadToInbox() error {
 
 // SendMessage is the information sent in an API request to Facebook.
 type SendMessage struct {
-	MessagingType MessagingType  `json:"messaging_type"`
-	Recipient     Recipient      `json:"recipient"`
-	Message       MessageData    `json:"message"`
-	Tag           string         `json:"tag,omitempty"`
-	ThreadControl *ThreadControl `json:"thread_control,omitempty"`
+	MessagingType    MessagingType    `json:"messaging_type"`
+	Recipient        Recipient        `json:"recipient"`
+	Message          MessageData      `json:"message"`
+	Tag              string           `json:"tag,omitempty"`
+	ThreadControl    *ThreadControl   `json:"thread_control,omitempty"`
+	PersonaID        string           `json:"persona_id,omitempty"`
+	NotificationType NotificationType `json:"notification_type,omitempty"`
 }
 
 // MessageData is a message consisting of text or an attachment, with an additional selection of optional quick replies.
@@ -510,6 +514,8 @@ type StructuredMessageAttachment struct {
 	Type  AttachmentType `json:"type"`
 	// Payload is the information for the file which was sent in the attachment.
 	Payload StructuredMessagePayload `json:"payload"`
+	// Template is the typed payload of a template. It is sent instead of Payload when set.
+	Template Template `json:"-"`
 }
 
 // StructuredMessagePayload is the actual payload of an attachment.
@@ -527,6 +533,9 @@ type StructuredMessagePayload struct {
 	ReceiptMessagePayload
 }
 
+// ReceiptMessagePayload is the receipt part of StructuredMessagePayload.
+//
+// Deprecated: use Receipt with Response.ReceiptTemplate which keeps the amounts exact.
 type ReceiptMessagePayload struct {
 	RecipientName string       `json:"recipient_name,omitempty"`
 	OrderNumber   string       `json:"order_number,omitempty"`
@@ -570,6 +579,9 @@ type StructuredMessageElement struct {
 	ReceiptMessageElement
 }
 
+// ReceiptMessageElement is the receipt part of StructuredMessageElement.
+//
+// Deprecated: use ReceiptElement of Receipt.
 type ReceiptMessageElement struct {
 	Quantity float32 `json:"quantity,omitempty"`
 	Price    float32 `json:"price,omitempty"`
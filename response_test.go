@@ -2,11 +2,13 @@ package messenger
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/h2non/gock"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -116,3 +118,45 @@ func TestResponse_checkFacebookError_ReturnsQueryError(t *testing.T) {
 	assert.Equal(t, "Invalid message id", queryError.Message)
 	assert.Equal(t, 508, queryError.Code)
 }
+
+func TestResponse_DispatchMessage_GraphURL(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2.11/me/messages", r.URL.Path)
+		assert.Equal(t, "access_token=token", r.URL.RawQuery)
+		fmt.Fprint(w, `{"message_id": "ABCD"}`)
+	}))
+	defer srv.Close()
+
+	m := New(Options{
+		Token:      "token",
+		HTTPClient: srv.Client(),
+		GraphURL:   srv.URL + "/",
+	})
+
+	resp, err := m.Response(154).Text("Hello World", ResponseType, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD", resp.MessageID)
+}
+
+func TestResponse_WithContext_Canceled(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not be sent")
+	}))
+	defer srv.Close()
+
+	m := New(Options{HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := m.ResponseWithContext(ctx, 154)
+	assert.Equal(t, ctx, r.Context())
+	assert.Equal(t, context.Background(), m.Response(154).Context())
+
+	_, err := r.Text("Hello World", ResponseType, nil, "")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,180 @@
+package messenger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultInitialBackoff is the delay before the first retry used when RetryPolicy.InitialBackoff is zero.
+	DefaultInitialBackoff = 200 * time.Millisecond
+	// DefaultMaxBackoff is the maximal delay between attempts used when RetryPolicy.MaxBackoff is zero.
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// DefaultRetryableCodes are the Graph API error codes retried when RetryPolicy.RetryableCodes is nil:
+// unknown error, service unavailable and the rate limiting codes.
+// https://developers.facebook.com/docs/graph-api/guides/error-handling
+var DefaultRetryableCodes = []int{1, 2, 4, 17, 32, 341, 613}
+
+// RetryPolicy configures how failed Graph API requests are retried. Network errors,
+// 5xx responses, transient errors and errors with one of the RetryableCodes are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximal number of attempts including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It is doubled on every next retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+	// RetryableCodes are the Graph API error codes which should be retried.
+	RetryableCodes []int
+	// OnAttempt is called after every attempt.
+	OnAttempt func(RetryAttempt)
+}
+
+// RetryAttempt describes a finished attempt of a Graph API request.
+type RetryAttempt struct {
+	// Attempt is the number of the attempt starting from 1.
+	Attempt int
+	// Request is the request which was sent.
+	Request *http.Request
+	// StatusCode is the HTTP status of the response. Zero if there was no response.
+	StatusCode int
+	// Err is the network error of the attempt if there's any.
+	Err error
+	// QueryError is the error sent back by Facebook if there's any.
+	QueryError *QueryError
+	// Retry is true if the request will be sent again after Backoff.
+	Retry bool
+	// Backoff is the delay before the next attempt.
+	Backoff time.Duration
+}
+
+// do sends the request until it succeeds, fails with a permanent error or the attempts are exhausted.
+func (p *RetryPolicy) do(client HTTPClient, req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		a := RetryAttempt{Attempt: attempt, Request: req}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			a.Err = err
+			a.Retry = req.Context().Err() == nil
+		} else {
+			a.StatusCode = resp.StatusCode
+
+			// consume a *copy* of the response body
+			content, readErr := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewBuffer(content))
+
+			if readErr != nil {
+				resp, err = nil, readErr
+				a.Err = readErr
+				a.Retry = true
+			} else {
+				a.QueryError = parseQueryError(content)
+				a.Retry = p.retryable(resp.StatusCode, a.QueryError)
+			}
+		}
+
+		if attempt >= p.MaxAttempts || (req.Body != nil && req.GetBody == nil) {
+			a.Retry = false
+		}
+
+		if a.Retry {
+			a.Backoff = p.backoff(attempt)
+		}
+
+		if p.OnAttempt != nil {
+			p.OnAttempt(a)
+		}
+
+		if !a.Retry {
+			return resp, err
+		}
+
+		timer := time.NewTimer(a.Backoff)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether the response should be retried.
+func (p *RetryPolicy) retryable(statusCode int, qe *QueryError) bool {
+	if statusCode >= http.StatusInternalServerError {
+		return true
+	}
+
+	if qe == nil {
+		return false
+	}
+
+	if qe.IsTransient {
+		return true
+	}
+
+	codes := p.RetryableCodes
+	if codes == nil {
+		codes = DefaultRetryableCodes
+	}
+
+	for _, code := range codes {
+		if qe.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns the exponential delay with jitter after the attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+
+	d := initial
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	// keep the half of the delay and randomize the rest
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// parseQueryError returns the error sent back by Facebook in the response body if there's any.
+func parseQueryError(content []byte) *QueryError {
+	qr := QueryResponse{}
+	if err := json.Unmarshal(content, &qr); err != nil {
+		return nil
+	}
+
+	return qr.Error
+}
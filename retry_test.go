@@ -0,0 +1,98 @@
+package messenger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_RetriesTransientErrors(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(body), "Hello World")
+
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"Calls to this api have exceeded the rate limit.","code":613}}`)
+		default:
+			fmt.Fprint(w, `{"message_id": "ABCD"}`)
+		}
+	}))
+	defer srv.Close()
+
+	var attempts []RetryAttempt
+	m := New(Options{
+		HTTPClient: srv.Client(),
+		GraphURL:   srv.URL,
+		Retry: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			OnAttempt: func(a RetryAttempt) {
+				attempts = append(attempts, a)
+			},
+		},
+	})
+
+	resp, err := m.Response(154).Text("Hello World", ResponseType, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD", resp.MessageID)
+	require.Len(t, attempts, 3)
+	assert.Equal(t, http.StatusBadGateway, attempts[0].StatusCode)
+	assert.True(t, attempts[0].Retry)
+	assert.Equal(t, 613, attempts[1].QueryError.Code)
+	assert.True(t, attempts[1].Retry)
+	assert.False(t, attempts[2].Retry)
+}
+
+func TestRetryPolicy_PermanentError(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"message":"Invalid parameter","code":100}}`)
+	}))
+	defer srv.Close()
+
+	m := New(Options{
+		HTTPClient: srv.Client(),
+		GraphURL:   srv.URL,
+		Retry:      &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+
+	_, err := m.Response(154).Text("Hello World", ResponseType, nil, "")
+	queryError, ok := err.(*QueryError)
+	require.True(t, ok)
+	assert.Equal(t, 100, queryError.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+
+	p := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt, max := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		5: time.Second,
+	} {
+		d := p.backoff(attempt)
+		assert.True(t, d >= max/2 && d <= max, "attempt %d: %s", attempt, d)
+	}
+}
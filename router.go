@@ -0,0 +1,206 @@
+package messenger
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PayloadRequest is a postback or a quick reply payload matched by the payload router.
+type PayloadRequest struct {
+	// Sender is who the payload was sent from.
+	Sender Sender
+	// Recipient is who the payload was sent to.
+	Recipient Recipient
+	// Time is when the payload was sent.
+	Time time.Time
+	// Platform is the platform the payload was sent on.
+	Platform Platform
+	// Payload is the raw payload.
+	Payload string
+	// Pattern is the matched pattern. Empty for the fallback handlers.
+	Pattern string
+	// Params are the values of the pattern parameters. The rest of a prefix pattern is named "*".
+	Params map[string]string
+	// PostBack is the postback the payload came with. Nil for the quick replies.
+	PostBack *PostBack
+	// Message is the message the quick reply payload came with. Nil for the postbacks.
+	Message *Message
+}
+
+// Param returns the value of the named pattern parameter.
+func (p PayloadRequest) Param(name string) string {
+	return p.Params[name]
+}
+
+// PayloadHandler is a handler used for responding to a routed postback or quick reply payload.
+type PayloadHandler func(PayloadRequest, *Response)
+
+// PayloadHandlerE is like PayloadHandler but returns an error which is reported to Options.OnError.
+type PayloadHandlerE func(PayloadRequest, *Response) error
+
+// payloadRoute is a compiled payload pattern.
+type payloadRoute struct {
+	pattern string
+	re      *regexp.Regexp
+	params  []string
+	handler PayloadHandlerE
+}
+
+// payloadRouter routes the payloads to the handlers of the first matching pattern.
+// The exact patterns are matched before the others.
+type payloadRouter struct {
+	exact    map[string]PayloadHandlerE
+	routes   []payloadRoute
+	fallback []PayloadHandlerE
+}
+
+var payloadParamRegexp = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// HandlePayload routes the postbacks and the quick replies with the payload matching the pattern to the handler.
+// The pattern is either an exact payload like "MENU", a prefix ending with "*" like "ORDER:*"
+// or a template like "ORDER:{id}:CANCEL" which parameters are passed in PayloadRequest.Params.
+// Exact patterns take precedence over the others which are matched in the order of registration.
+// The PostBackHandlers and the MessageHandlers are triggered by the routed payloads as well.
+// It panics if the pattern is empty or already registered.
+func (m *Messenger) HandlePayload(pattern string, f PayloadHandler) {
+	m.HandlePayloadE(pattern, func(p PayloadRequest, r *Response) error {
+		f(p, r)
+		return nil
+	})
+}
+
+// HandlePayloadE is like HandlePayload but the handler returns an error.
+func (m *Messenger) HandlePayloadE(pattern string, f PayloadHandlerE) {
+	if pattern == "" {
+		panic("messenger: empty payload pattern")
+	}
+
+	r := m.payloadRouter()
+	if r.registered(pattern) {
+		panic("messenger: multiple registrations for payload pattern " + pattern)
+	}
+
+	if !strings.HasSuffix(pattern, "*") && !payloadParamRegexp.MatchString(pattern) {
+		r.exact[pattern] = f
+		return
+	}
+
+	r.routes = append(r.routes, compilePayloadRoute(pattern, f))
+}
+
+// HandlePayloadFallback adds a handler triggered by the payloads not matching any pattern.
+func (m *Messenger) HandlePayloadFallback(f PayloadHandler) {
+	m.HandlePayloadFallbackE(func(p PayloadRequest, r *Response) error {
+		f(p, r)
+		return nil
+	})
+}
+
+// HandlePayloadFallbackE is like HandlePayloadFallback but the handler returns an error.
+func (m *Messenger) HandlePayloadFallbackE(f PayloadHandlerE) {
+	r := m.payloadRouter()
+	r.fallback = append(r.fallback, f)
+}
+
+func (m *Messenger) payloadRouter() *payloadRouter {
+	if m.router == nil {
+		m.router = &payloadRouter{exact: map[string]PayloadHandlerE{}}
+	}
+
+	return m.router
+}
+
+// registered reports whether the pattern has a handler already.
+func (r *payloadRouter) registered(pattern string) bool {
+	if _, ok := r.exact[pattern]; ok {
+		return true
+	}
+
+	for _, route := range r.routes {
+		if route.pattern == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compilePayloadRoute converts the pattern into an anchored regular expression.
+func compilePayloadRoute(pattern string, f PayloadHandlerE) payloadRoute {
+	route := payloadRoute{pattern: pattern, handler: f}
+
+	rest := pattern
+	prefix := strings.HasSuffix(rest, "*")
+	if prefix {
+		rest = strings.TrimSuffix(rest, "*")
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for {
+		loc := payloadParamRegexp.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			break
+		}
+
+		expr.WriteString(regexp.QuoteMeta(rest[:loc[0]]))
+		expr.WriteString("(.+?)")
+		route.params = append(route.params, rest[loc[2]:loc[3]])
+		rest = rest[loc[1]:]
+	}
+
+	expr.WriteString(regexp.QuoteMeta(rest))
+
+	if prefix {
+		expr.WriteString("(.*)")
+		route.params = append(route.params, "*")
+	}
+
+	expr.WriteString("$")
+	route.re = regexp.MustCompile(expr.String())
+
+	return route
+}
+
+// match reports whether the payload matches the route and returns the values of the pattern parameters.
+func (r payloadRoute) match(payload string) (map[string]string, bool) {
+	match := r.re.FindStringSubmatch(payload)
+	if match == nil {
+		return nil, false
+	}
+
+	params := make(map[string]string, len(r.params))
+	for i, name := range r.params {
+		params[name] = match[i+1]
+	}
+
+	return params, true
+}
+
+// route triggers the handlers of the pattern matching the payload or the fallback ones.
+func (r *payloadRouter) route(p PayloadRequest, resp *Response, call func(func() error)) {
+	if f, ok := r.exact[p.Payload]; ok {
+		p.Pattern = p.Payload
+		call(func() error { return f(p, resp) })
+		return
+	}
+
+	for _, route := range r.routes {
+		params, ok := route.match(p.Payload)
+		if !ok {
+			continue
+		}
+
+		p.Pattern = route.pattern
+		p.Params = params
+
+		call(func() error { return route.handler(p, resp) })
+		return
+	}
+
+	for _, f := range r.fallback {
+		call(func() error { return f(p, resp) })
+	}
+}
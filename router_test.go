@@ -0,0 +1,71 @@
+package messenger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessenger_HandlePayload(t *testing.T) {
+	t.Parallel()
+
+	m := &Messenger{}
+
+	var routed []string
+	m.HandlePayload("ORDER:{id}:CANCEL", func(p PayloadRequest, r *Response) {
+		routed = append(routed, "cancel "+p.Param("id"))
+		assert.NotNil(t, p.PostBack)
+		assert.EqualValues(t, 111, p.Sender.ID)
+	})
+	m.HandlePayload("ORDER:*", func(p PayloadRequest, r *Response) {
+		routed = append(routed, "order "+p.Param("*"))
+	})
+	m.HandlePayload("MENU", func(p PayloadRequest, r *Response) {
+		routed = append(routed, "menu "+p.Pattern)
+	})
+	m.HandlePayload("SIZE:{size}:{color}", func(p PayloadRequest, r *Response) {
+		routed = append(routed, "size "+p.Param("size")+" "+p.Param("color"))
+		assert.NotNil(t, p.Message)
+	})
+	m.HandlePayloadFallback(func(p PayloadRequest, r *Response) {
+		routed = append(routed, "fallback "+p.Payload)
+	})
+
+	postback := func(payload string) MessageInfo {
+		return MessageInfo{Sender: Sender{ID: 111}, PostBack: &PostBack{Payload: payload}}
+	}
+	quickReply := func(payload string) MessageInfo {
+		return MessageInfo{Sender: Sender{ID: 111}, Message: &Message{QuickReply: &QuickReply{Payload: payload}}}
+	}
+
+	m.dispatch(context.Background(), Receive{Entry: []Entry{{Messaging: []MessageInfo{
+		postback("ORDER:42:CANCEL"),
+		postback("ORDER:42:SHIP"),
+		postback("MENU"),
+		quickReply("SIZE:XL:red"),
+		quickReply("SIZE:XL"),
+		{Message: &Message{Text: "no payload"}},
+		{Message: &Message{IsEcho: true, QuickReply: &QuickReply{Payload: "MENU"}}},
+	}}}})
+
+	assert.Equal(t, []string{
+		"cancel 42",
+		"order 42:SHIP",
+		"menu MENU",
+		"size XL red",
+		"fallback SIZE:XL",
+	}, routed)
+}
+
+func TestMessenger_HandlePayload_Duplicate(t *testing.T) {
+	t.Parallel()
+
+	m := New(Options{})
+	m.HandlePayload("MENU", func(PayloadRequest, *Response) {})
+	m.HandlePayload("ORDER:*", func(PayloadRequest, *Response) {})
+
+	assert.Panics(t, func() { m.HandlePayload("MENU", func(PayloadRequest, *Response) {}) })
+	assert.Panics(t, func() { m.HandlePayload("ORDER:*", func(PayloadRequest, *Response) {}) })
+	assert.Panics(t, func() { m.HandlePayload("", func(PayloadRequest, *Response) {}) })
+}
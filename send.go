@@ -0,0 +1,153 @@
+package messenger
+
+import (
+	"context"
+)
+
+// NotificationType is the push notification type of a message.
+type NotificationType string
+
+const (
+	// RegularNotification is a sound or vibration notification. Default.
+	RegularNotification NotificationType = "REGULAR"
+	// SilentPushNotification is an on-screen notification only.
+	SilentPushNotification NotificationType = "SILENT_PUSH"
+	// NoPushNotification is no notification.
+	NoPushNotification NotificationType = "NO_PUSH"
+)
+
+// SendOption configures a message sent with Response.Send.
+type SendOption func(*SendMessage)
+
+// WithMessagingType sets the messaging type of the message. It is RESPONSE by default.
+func WithMessagingType(messagingType MessagingType) SendOption {
+	return func(m *SendMessage) {
+		if messagingType != "" {
+			m.MessagingType = messagingType
+		}
+	}
+}
+
+// WithTag makes the message a MESSAGE_TAG one with the tag.
+func WithTag(tag string) SendOption {
+	return func(m *SendMessage) {
+		if tag != "" {
+			m.MessagingType = MessageTagType
+			m.Tag = tag
+		}
+	}
+}
+
+// WithPersona sends the message on behalf of the persona.
+func WithPersona(personaID string) SendOption {
+	return func(m *SendMessage) {
+		m.PersonaID = personaID
+	}
+}
+
+// WithNotificationType sets the push notification type of the message.
+func WithNotificationType(notificationType NotificationType) SendOption {
+	return func(m *SendMessage) {
+		m.NotificationType = notificationType
+	}
+}
+
+// WithThreadControl sets the thread control of the message.
+func WithThreadControl(control *ThreadControl) SendOption {
+	return func(m *SendMessage) {
+		m.ThreadControl = control
+	}
+}
+
+// WithMetadata sets the metadata passed to the other apps of the handover protocol.
+func WithMetadata(metadata string) SendOption {
+	return func(m *SendMessage) {
+		m.Message.Metadata = metadata
+	}
+}
+
+// legacyOptions converts the positional arguments of the older send methods into the options.
+func legacyOptions(messagingType MessagingType, control *ThreadControl, metadata string, tags []string) []SendOption {
+	opts := make([]SendOption, 0, 4)
+	if len(tags) > 0 {
+		opts = append(opts, WithTag(tags[0]))
+	}
+
+	return append(opts, WithMessagingType(messagingType), WithThreadControl(control), WithMetadata(metadata))
+}
+
+// NewAttachment returns the attachment of an image, sound, video or a regular file by URL.
+func NewAttachment(dataType AttachmentType, url string) *StructuredMessageAttachment {
+	return &StructuredMessageAttachment{Type: dataType, Payload: StructuredMessagePayload{Url: url}}
+}
+
+// NewGenericTemplate returns the generic template attachment of the elements.
+func NewGenericTemplate(elements ...StructuredMessageElement) *StructuredMessageAttachment {
+	return &StructuredMessageAttachment{
+		Type: "template",
+		Payload: StructuredMessagePayload{
+			TemplateType: "generic",
+			Elements:     &elements,
+		},
+	}
+}
+
+// NewButtonTemplate returns the button template attachment.
+func NewButtonTemplate(text string, buttons ...StructuredMessageButton) *StructuredMessageAttachment {
+	return &StructuredMessageAttachment{
+		Type: "template",
+		Payload: StructuredMessagePayload{
+			TemplateType: "button",
+			Text:         text,
+			Buttons:      &buttons,
+		},
+	}
+}
+
+// NewListTemplate returns the compact list template attachment of the elements.
+func NewListTemplate(elements ...StructuredMessageElement) *StructuredMessageAttachment {
+	return &StructuredMessageAttachment{
+		Type: "template",
+		Payload: StructuredMessagePayload{
+			TopElementStyle: CompactTopElementStyle,
+			TemplateType:    "list",
+			Elements:        &elements,
+		},
+	}
+}
+
+// Send sends the message of any kind: a text or an attachment with optional quick replies.
+// The message is checked against the documented limits of the Send API and nothing is sent
+// when it is invalid, the *ValidationError lists every violation then.
+func (r *Response) Send(ctx context.Context, msg MessageData, opts ...SendOption) (QueryResponse, error) {
+	m := SendMessage{
+		MessagingType: ResponseType,
+		Recipient:     r.to,
+		Message:       msg,
+	}
+
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	return r.send(ctx, &m)
+}
+
+// send validates and posts the message.
+func (r *Response) send(ctx context.Context, m *SendMessage) (QueryResponse, error) {
+	if err := validateSendMessage(m); err != nil {
+		return QueryResponse{}, err
+	}
+
+	return r.DispatchMessageCtx(ctx, m)
+}
+
+// SendTo sends the message to the recipient like Response.Send.
+func (m *Messenger) SendTo(
+	ctx context.Context,
+	to Recipient,
+	msg MessageData,
+	opts ...SendOption,
+) (QueryResponse, error) {
+	return m.newResponse(to).Send(ctx, msg, opts...)
+}
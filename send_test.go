@@ -0,0 +1,121 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSendServer(t *testing.T, bodies *[]string) (*Messenger, func()) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		*bodies = append(*bodies, string(body))
+		fmt.Fprint(w, `{"message_id": "ABCD"}`)
+	}))
+
+	return New(Options{HTTPClient: srv.Client(), GraphURL: srv.URL}), srv.Close
+}
+
+func TestResponse_Send(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	m, closeServer := newSendServer(t, &bodies)
+	defer closeServer()
+
+	resp, err := m.Response(154).Send(context.Background(),
+		MessageData{Text: "Hello", QuickReplies: []QuickReply{{ContentType: "text", Title: "Hi", Payload: "HI"}}},
+		WithTag("ACCOUNT_UPDATE"),
+		WithPersona("42"),
+		WithNotificationType(SilentPushNotification),
+		WithThreadControl(&ThreadControl{Payload: PassThreadControl}),
+		WithMetadata("meta"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD", resp.MessageID)
+
+	require.Len(t, bodies, 1)
+	assert.JSONEq(t, `{
+		"messaging_type": "MESSAGE_TAG",
+		"tag": "ACCOUNT_UPDATE",
+		"recipient": {"id": "154"},
+		"persona_id": "42",
+		"notification_type": "SILENT_PUSH",
+		"thread_control": {"payload": "pass_thread_control"},
+		"message": {
+			"text": "Hello",
+			"quick_replies": [{"content_type": "text", "title": "Hi", "payload": "HI"}],
+			"metadata": "meta"
+		}
+	}`, bodies[0])
+}
+
+func TestResponse_Send_Invalid(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	m, closeServer := newSendServer(t, &bodies)
+	defer closeServer()
+
+	_, err := m.SendTo(context.Background(), Recipient{ID: 154}, MessageData{Attachment: NewGenericTemplate()})
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr), err)
+	assert.Equal(t, []Violation{
+		{Field: "message.attachment.payload.elements", Message: "0 elements, at least 1 required"},
+	}, verr.Violations)
+	assert.Empty(t, bodies)
+}
+
+func TestResponse_ButtonTemplate_Legacy(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	m, closeServer := newSendServer(t, &bodies)
+	defer closeServer()
+
+	buttons := []StructuredMessageButton{{Type: "postback", Title: "Buy", Payload: "BUY"}}
+	_, err := m.Response(154).ButtonTemplate("Pick", &buttons, UpdateType, "meta")
+	require.NoError(t, err)
+
+	elements := []StructuredMessageElement{{Title: "One"}, {Title: "Two"}}
+	_, err = m.Response(154).ListTemplate(&elements, MessageTagType, "ACCOUNT_UPDATE")
+	require.NoError(t, err)
+
+	require.Len(t, bodies, 2)
+	assert.JSONEq(t, `{
+		"messaging_type": "UPDATE",
+		"recipient": {"id": "154"},
+		"message": {
+			"attachment": {"type": "template", "payload": {
+				"template_type": "button",
+				"text": "Pick",
+				"buttons": [{"type": "postback", "title": "Buy", "payload": "BUY"}]
+			}},
+			"metadata": "meta"
+		}
+	}`, bodies[0])
+	assert.JSONEq(t, `{
+		"messaging_type": "MESSAGE_TAG",
+		"tag": "ACCOUNT_UPDATE",
+		"recipient": {"id": "154"},
+		"message": {
+			"attachment": {"type": "template", "payload": {
+				"template_type": "list",
+				"top_element_style": "compact",
+				"elements": [
+					{"title": "One", "image_url": "", "subtitle": ""},
+					{"title": "Two", "image_url": "", "subtitle": ""}
+				]
+			}}
+		}
+	}`, bodies[1])
+}
@@ -0,0 +1,168 @@
+package messenger
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Template is the typed payload of a template message, e.g. *Receipt.
+// It is sent as the attachment made by NewTemplate.
+type Template interface {
+	// TemplateType returns the template_type of the payload.
+	TemplateType() string
+	// validate checks the payload against the documented limits of the template.
+	validate(v *validator, field string)
+}
+
+// NewTemplate returns the attachment of the template.
+func NewTemplate(t Template) *StructuredMessageAttachment {
+	return &StructuredMessageAttachment{Type: "template", Template: t}
+}
+
+// MarshalJSON implements json.Marshaler. The typed Template replaces the Payload when set.
+func (a StructuredMessageAttachment) MarshalJSON() ([]byte, error) {
+	type attachment StructuredMessageAttachment
+	if a.Template == nil {
+		return json.Marshal(attachment(a))
+	}
+
+	return json.Marshal(struct {
+		Title   string         `json:"title,omitempty"`
+		URL     string         `json:"url,omitempty"`
+		Type    AttachmentType `json:"type"`
+		Payload Template       `json:"payload"`
+	}{
+		Title:   a.Title,
+		URL:     a.URL,
+		Type:    a.Type,
+		Payload: a.Template,
+	})
+}
+
+// MediaType is the type of the media of a media template.
+type MediaType string
+
+const (
+	// ImageMedia is an image or a GIF.
+	ImageMedia MediaType = "image"
+	// VideoMedia is a video.
+	VideoMedia MediaType = "video"
+)
+
+// MaxMediaButtons is the maximal number of the buttons of a media template.
+const MaxMediaButtons = 1
+
+// MaxProductElements is the maximal number of the products of a product template.
+const MaxProductElements = 10
+
+// Media is the payload of a media template with an image or a video. Either URL of a Facebook post
+// or AttachmentID of an uploaded attachment is required.
+type Media struct {
+	MediaType    MediaType                 `json:"media_type"`
+	URL          string                    `json:"url,omitempty"`
+	AttachmentID string                    `json:"attachment_id,omitempty"`
+	Buttons      []StructuredMessageButton `json:"buttons,omitempty"`
+	// Sharable enables the native share button.
+	Sharable bool `json:"-"`
+}
+
+// TemplateType implements Template.
+func (m *Media) TemplateType() string {
+	return "media"
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *Media) MarshalJSON() ([]byte, error) {
+	type media Media
+	return json.Marshal(struct {
+		TemplateType string   `json:"template_type"`
+		Sharable     bool     `json:"sharable,omitempty"`
+		Elements     []*media `json:"elements"`
+	}{m.TemplateType(), m.Sharable, []*media{(*media)(m)}})
+}
+
+func (m *Media) validate(v *validator, field string) {
+	field += ".elements[0]"
+
+	switch m.MediaType {
+	case ImageMedia, VideoMedia:
+	case "":
+		v.add(field+".media_type", "is required")
+	default:
+		v.add(field+".media_type", "%q is not image or video", m.MediaType)
+	}
+
+	switch {
+	case m.URL == "" && m.AttachmentID == "":
+		v.add(field, "either url or attachment_id is required")
+	case m.URL != "" && m.AttachmentID != "":
+		v.add(field, "url and attachment_id are mutually exclusive")
+	}
+
+	if len(m.Buttons) > MaxMediaButtons {
+		v.add(field+".buttons", "%d buttons, at most %d allowed", len(m.Buttons), MaxMediaButtons)
+	}
+	v.buttons(field+".buttons", m.Buttons)
+}
+
+// Products is the payload of a product template showing the products of the catalog connected to the page.
+type Products struct {
+	// IDs are the IDs of the products in the catalog. More than one product is shown as a carousel.
+	IDs []string
+}
+
+// TemplateType implements Template.
+func (p *Products) TemplateType() string {
+	return "product"
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *Products) MarshalJSON() ([]byte, error) {
+	type product struct {
+		ID string `json:"id"`
+	}
+
+	elements := make([]product, len(p.IDs))
+	for i, id := range p.IDs {
+		elements[i] = product{ID: id}
+	}
+
+	return json.Marshal(struct {
+		TemplateType string    `json:"template_type"`
+		Elements     []product `json:"elements"`
+	}{p.TemplateType(), elements})
+}
+
+func (p *Products) validate(v *validator, field string) {
+	switch {
+	case len(p.IDs) == 0:
+		v.add(field+".elements", "at least 1 product is required")
+	case len(p.IDs) > MaxProductElements:
+		v.add(field+".elements", "%d products, at most %d allowed", len(p.IDs), MaxProductElements)
+	}
+
+	for i, id := range p.IDs {
+		v.required(fmt.Sprintf("%s.elements[%d].id", field, i), id)
+	}
+}
+
+// MediaTemplate sends the media template with an image or a video.
+// Nothing is sent when the media is invalid, the *ValidationError lists every violation then.
+func (r *Response) MediaTemplate(media Media, opts ...SendOption) (QueryResponse, error) {
+	return r.Send(r.Context(), MessageData{Attachment: NewTemplate(&media)}, opts...)
+}
+
+// ProductTemplate sends the product template with the products of the catalog.
+func (r *Response) ProductTemplate(products Products, opts ...SendOption) (QueryResponse, error) {
+	return r.Send(r.Context(), MessageData{Attachment: NewTemplate(&products)}, opts...)
+}
+
+// MediaTemplate sends the media template to the recipient.
+func (m *Messenger) MediaTemplate(to Recipient, media Media, opts ...SendOption) (QueryResponse, error) {
+	return m.newResponse(to).MediaTemplate(media, opts...)
+}
+
+// ProductTemplate sends the product template to the recipient.
+func (m *Messenger) ProductTemplate(to Recipient, products Products, opts ...SendOption) (QueryResponse, error) {
+	return m.newResponse(to).ProductTemplate(products, opts...)
+}
@@ -0,0 +1,142 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse_Templates(t *testing.T) {
+	t.Parallel()
+
+	var bodies []string
+	m, closeServer := newSendServer(t, &bodies)
+	defer closeServer()
+
+	r := m.Response(154)
+
+	_, err := r.MediaTemplate(Media{
+		MediaType:    VideoMedia,
+		AttachmentID: "1234",
+		Buttons:      []StructuredMessageButton{{Type: "web_url", Title: "Open", URL: "https://example.com"}},
+		Sharable:     true,
+	})
+	require.NoError(t, err)
+
+	_, err = m.ProductTemplate(Recipient{ID: 154}, Products{IDs: []string{"1", "2"}})
+	require.NoError(t, err)
+
+	_, err = r.CustomerFeedbackTemplate(CustomerFeedback{
+		Title:       "Rate your experience",
+		ButtonTitle: "Rate",
+		Question: FeedbackQuestion{
+			ID:                  "delivery",
+			Type:                CSATQuestion,
+			ScoreOption:         FiveStarsScore,
+			FollowUpPlaceholder: "Tell us more",
+		},
+		PrivacyURL:    "https://example.com/privacy",
+		ExpiresInDays: 3,
+	}, WithTag("POST_PURCHASE_UPDATE"))
+	require.NoError(t, err)
+
+	require.Len(t, bodies, 3)
+	assert.JSONEq(t, `{
+		"messaging_type": "RESPONSE",
+		"recipient": {"id": "154"},
+		"message": {"attachment": {"type": "template", "payload": {
+			"template_type": "media",
+			"sharable": true,
+			"elements": [{
+				"media_type": "video",
+				"attachment_id": "1234",
+				"buttons": [{"type": "web_url", "title": "Open", "url": "https://example.com"}]
+			}]
+		}}}
+	}`, bodies[0])
+	assert.JSONEq(t, `{
+		"messaging_type": "RESPONSE",
+		"recipient": {"id": "154"},
+		"message": {"attachment": {"type": "template", "payload": {
+			"template_type": "product",
+			"elements": [{"id": "1"}, {"id": "2"}]
+		}}}
+	}`, bodies[1])
+	assert.JSONEq(t, `{
+		"messaging_type": "MESSAGE_TAG",
+		"tag": "POST_PURCHASE_UPDATE",
+		"recipient": {"id": "154"},
+		"message": {"attachment": {"type": "template", "payload": {
+			"template_type": "customer_feedback",
+			"title": "Rate your experience",
+			"button_title": "Rate",
+			"feedback_screens": [{"questions": [{
+				"id": "delivery",
+				"type": "csat",
+				"score_option": "five_stars",
+				"follow_up": {"type": "free_form", "placeholder": "Tell us more"}
+			}]}],
+			"business_privacy": {"url": "https://example.com/privacy"},
+			"expires_in_days": 3
+		}}}
+	}`, bodies[2])
+}
+
+func TestTemplates_Validation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		template   Template
+		violations []Violation
+	}{
+		"media": {
+			template: &Media{MediaType: "gif", URL: "https://example.com", AttachmentID: "1"},
+			violations: []Violation{
+				{Field: "message.attachment.payload.elements[0].media_type", Message: `"gif" is not image or video`},
+				{Field: "message.attachment.payload.elements[0]", Message: "url and attachment_id are mutually exclusive"},
+			},
+		},
+		"products": {
+			template: &Products{},
+			violations: []Violation{
+				{Field: "message.attachment.payload.elements", Message: "at least 1 product is required"},
+			},
+		},
+		"feedback": {
+			template: &CustomerFeedback{
+				Title:         "Rate",
+				ButtonTitle:   "Rate",
+				PrivacyURL:    "https://example.com/privacy",
+				ExpiresInDays: 8,
+				Question:      FeedbackQuestion{ID: "my question", Type: NPSQuestion, ScoreOption: FiveStarsScore},
+			},
+			violations: []Violation{
+				{Field: "message.attachment.payload.expires_in_days", Message: "must be between 1 and 7"},
+				{
+					Field:   "message.attachment.payload.feedback_screens[0].questions[0].id",
+					Message: `"my question" must contain only letters, digits and underscores`,
+				},
+				{
+					Field:   "message.attachment.payload.feedback_screens[0].questions[0].score_option",
+					Message: `"five_stars" is not allowed for the nps questions`,
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := (&Response{}).Send(context.Background(), MessageData{Attachment: NewTemplate(test.template)})
+
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr), err)
+			assert.Equal(t, test.violations, verr.Violations)
+		})
+	}
+}
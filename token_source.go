@@ -0,0 +1,158 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// InvalidTokenErrorCode is the code of the QueryError (OAuthException) returned for the expired
+// or invalidated access tokens.
+const InvalidTokenErrorCode = 190
+
+// TokenSource provides the access token for each outbound Graph API request.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticTokenSource is the TokenSource of the access token which never changes.
+type StaticTokenSource string
+
+// Token implements TokenSource.
+func (s StaticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+// expirer is implemented by the TokenSources which replace the tokens rejected by the Graph API.
+type expirer interface {
+	// Expire marks the token as no longer valid.
+	Expire(token string)
+}
+
+// secretSource is implemented by the TokenSources of the tokens signed with their own app secret.
+type secretSource interface {
+	proofSecret() string
+}
+
+// CachingTokenSource caches the access token and refreshes it when the Graph API rejects it
+// with the InvalidTokenErrorCode. The request is repeated once with the refreshed token then.
+type CachingTokenSource struct {
+	// Initial is the token used until the first refresh, e.g. the persisted one. Leaving it blank
+	// implies refreshing before the first request.
+	Initial string
+	// Refresh obtains the new access token. Required.
+	Refresh func(ctx context.Context) (string, error)
+	// OnRefresh is called with every refreshed token, e.g. to persist it. It is called by the request
+	// which has refreshed the token, the other ones proceed meanwhile.
+	OnRefresh func(token string)
+
+	mu         sync.Mutex
+	token      string
+	started    bool
+	refreshing *tokenRefresh
+}
+
+// tokenRefresh is the refresh in flight. The concurrent Token calls wait for its result.
+type tokenRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// Token implements TokenSource. The concurrent calls wait for a single refresh until their contexts are done.
+func (s *CachingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	s.start()
+
+	if token := s.token; token != "" {
+		s.mu.Unlock()
+		return token, nil
+	}
+
+	if s.Refresh == nil {
+		s.mu.Unlock()
+		return "", errors.New("missing access token refresh function")
+	}
+
+	if r := s.refreshing; r != nil {
+		s.mu.Unlock()
+
+		select {
+		case <-r.done:
+			return r.token, r.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	r := &tokenRefresh{done: make(chan struct{})}
+	s.refreshing = r
+	s.mu.Unlock()
+
+	s.refresh(ctx, r)
+	if r.err != nil {
+		return "", r.err
+	}
+
+	if s.OnRefresh != nil {
+		s.OnRefresh(r.token)
+	}
+
+	return r.token, nil
+}
+
+// refresh calls Refresh and passes its result to the waiting Token calls.
+func (s *CachingTokenSource) refresh(ctx context.Context, r *tokenRefresh) {
+	defer func() {
+		s.mu.Lock()
+		if r.err == nil {
+			s.token = r.token
+		}
+		s.refreshing = nil
+		s.mu.Unlock()
+
+		close(r.done)
+	}()
+
+	// the error is left for the waiting calls if Refresh panics
+	r.err = errors.New("access token refresh failed")
+	r.token, r.err = s.Refresh(ctx)
+}
+
+// Expire makes the next Token call refresh the token unless it is refreshed already.
+func (s *CachingTokenSource) Expire(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.start()
+
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+func (s *CachingTokenSource) start() {
+	if !s.started {
+		s.token, s.started = s.Initial, true
+	}
+}
+
+// AppSecretTokenSource is the TokenSource of the tokens signed with their own app secret instead
+// of Options.AppSecret, e.g. the tokens of a page connected to another app. The empty AppSecret
+// disables appsecret_proof for the tokens.
+type AppSecretTokenSource struct {
+	TokenSource
+	AppSecret string
+}
+
+// Expire implements expirer if the underlying TokenSource does.
+func (s AppSecretTokenSource) Expire(token string) {
+	if e, ok := s.TokenSource.(expirer); ok {
+		e.Expire(token)
+	}
+}
+
+// proofSecret returns the app secret signing the tokens.
+func (s AppSecretTokenSource) proofSecret() string {
+	return s.AppSecret
+}
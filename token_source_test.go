@@ -0,0 +1,177 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenServer(t *testing.T, valid string, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		token := r.URL.Query().Get("access_token")
+		*requests = append(*requests, token+" "+string(body))
+
+		if token != valid {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": {"message": "Error validating access token", "type": "OAuthException", "code": 190}}`)
+			return
+		}
+		fmt.Fprint(w, `{"recipient_id": "154", "message_id": "mid"}`)
+	}))
+}
+
+func TestCachingTokenSource(t *testing.T) {
+	t.Parallel()
+
+	var requests []string
+	srv := newTokenServer(t, "new", &requests)
+	defer srv.Close()
+
+	var refreshed []string
+	src := &CachingTokenSource{
+		Initial: "old",
+		Refresh: func(context.Context) (string, error) {
+			return "new", nil
+		},
+		OnRefresh: func(token string) {
+			refreshed = append(refreshed, token)
+		},
+	}
+
+	m := New(Options{TokenSource: src, HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	_, err := m.SendTo(context.Background(), Recipient{ID: 154}, MessageData{Text: "first"})
+	require.NoError(t, err)
+	_, err = m.SendTo(context.Background(), Recipient{ID: 154}, MessageData{Text: "second"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"new"}, refreshed)
+	require.Len(t, requests, 3)
+	assert.Equal(t, requests[0][len("old"):], requests[1][len("new"):])
+	assert.Contains(t, requests[0], `old {"messaging_type":"RESPONSE"`)
+	assert.Contains(t, requests[1], `new {"messaging_type":"RESPONSE"`)
+	assert.Contains(t, requests[2], `"text":"second"`)
+
+	// the token refreshed by another request is not expired
+	src.Expire("old")
+	token, err := src.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "new", token)
+}
+
+func TestCachingTokenSource_RefreshFailure(t *testing.T) {
+	t.Parallel()
+
+	src := &CachingTokenSource{
+		Refresh: func(context.Context) (string, error) {
+			return "", errors.New("refresh failed")
+		},
+	}
+
+	r := &Response{to: Recipient{ID: 154}}
+	r.SetTokenSource(src)
+
+	_, err := r.Send(context.Background(), MessageData{Text: "hello"})
+	assert.EqualError(t, err, "refresh failed")
+}
+
+func TestCachingTokenSource_ConcurrentRefresh(t *testing.T) {
+	t.Parallel()
+
+	var (
+		refreshes int32
+		started   = make(chan struct{})
+		release   = make(chan struct{})
+	)
+	src := &CachingTokenSource{
+		Refresh: func(context.Context) (string, error) {
+			if atomic.AddInt32(&refreshes, 1) == 1 {
+				close(started)
+			}
+			<-release
+			return "new", nil
+		},
+	}
+	src.OnRefresh = func(token string) {
+		// the lock is not held by OnRefresh
+		current, err := src.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, token, current)
+	}
+
+	tokens := make(chan string, 10)
+	for i := 0; i < cap(tokens); i++ {
+		go func() {
+			token, err := src.Token(context.Background())
+			assert.NoError(t, err)
+			tokens <- token
+		}()
+	}
+
+	<-started
+
+	// the waiting call gives up when its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := src.Token(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	close(release)
+	for i := 0; i < cap(tokens); i++ {
+		assert.Equal(t, "new", <-tokens)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+}
+
+func TestStaticTokenSource_InvalidToken(t *testing.T) {
+	t.Parallel()
+
+	var requests []string
+	srv := newTokenServer(t, "new", &requests)
+	defer srv.Close()
+
+	m := New(Options{Token: "old", HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	_, err := m.SendTo(context.Background(), Recipient{ID: 154}, MessageData{Text: "hello"})
+
+	var qerr *QueryError
+	require.True(t, errors.As(err, &qerr), err)
+	assert.Equal(t, InvalidTokenErrorCode, qerr.Code)
+	assert.Len(t, requests, 1)
+}
+
+func TestAppSecretTokenSource_Refresh(t *testing.T) {
+	t.Parallel()
+
+	var requests []string
+	srv := newTokenServer(t, "new", &requests)
+	defer srv.Close()
+
+	src := AppSecretTokenSource{
+		TokenSource: &CachingTokenSource{
+			Initial: "old",
+			Refresh: func(context.Context) (string, error) {
+				return "new", nil
+			},
+		},
+		AppSecret: "other",
+	}
+
+	m := New(Options{TokenSource: src, HTTPClient: srv.Client(), GraphURL: srv.URL})
+
+	_, err := m.SendTo(context.Background(), Recipient{ID: 154}, MessageData{Text: "hello"})
+	require.NoError(t, err)
+	assert.Len(t, requests, 2)
+}
@@ -0,0 +1,244 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// The documented limits of the Send API.
+const (
+	// MaxTextLength is the maximal length of a text message.
+	MaxTextLength = 2000
+	// MaxQuickReplies is the maximal number of the quick replies of a message.
+	MaxQuickReplies = 13
+	// MaxQuickReplyTitleLength is the maximal length of a quick reply title.
+	MaxQuickReplyTitleLength = 20
+	// MaxButtonTitleLength is the maximal length of a button title.
+	MaxButtonTitleLength = 20
+	// MaxMetadataLength is the maximal length of the message metadata.
+	MaxMetadataLength = 1000
+	// MaxGenericElements is the maximal number of the elements of a generic template.
+	MaxGenericElements = 10
+	// MaxElementButtons is the maximal number of the buttons of a generic template element.
+	MaxElementButtons = 3
+	// MaxElementTitleLength is the maximal length of a template element title and subtitle.
+	MaxElementTitleLength = 80
+	// MaxButtonTemplateTextLength is the maximal length of the text of a button template.
+	MaxButtonTemplateTextLength = 640
+	// MaxTemplateButtons is the maximal number of the buttons of a button template.
+	MaxTemplateButtons = 3
+	// MinListElements is the minimal number of the elements of a list template.
+	MinListElements = 2
+	// MaxListElements is the maximal number of the elements of a list template.
+	MaxListElements = 4
+)
+
+// Violation is a single broken limit of a message.
+type Violation struct {
+	// Field is the path of the invalid field, e.g. "message.quick_replies[2].title".
+	Field string
+	// Message describes the violation.
+	Message string
+}
+
+// ValidationError is returned when a message or a profile breaks the limits of the API.
+// It lists every violation found.
+type ValidationError struct {
+	Violations []Violation
+
+	subject string
+}
+
+// ValidationError implements error.
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + ": " + v.Message
+	}
+
+	subject := e.subject
+	if subject == "" {
+		subject = "message"
+	}
+
+	return "invalid " + subject + ": " + strings.Join(parts, "; ")
+}
+
+// validator collects the violations of the subject, the message by default.
+type validator struct {
+	subject    string
+	violations []Violation
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// maxLength checks the length of the value in characters.
+func (v *validator) maxLength(field, value string, max int) {
+	if n := utf8.RuneCountInString(value); n > max {
+		v.add(field, "%d characters, at most %d allowed", n, max)
+	}
+}
+
+// required checks the value is not blank.
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+	}
+}
+
+// err returns the ValidationError or nil if there are no violations.
+func (v *validator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Violations: v.violations, subject: v.subject}
+}
+
+// validateSendMessage checks the message against the documented limits of the Send API.
+func validateSendMessage(m *SendMessage) error {
+	v := &validator{}
+
+	if m.MessagingType == MessageTagType && m.Tag == "" {
+		v.add("tag", "is required for the %s messaging type", MessageTagType)
+	}
+	if m.Tag != "" && m.MessagingType != MessageTagType {
+		v.add("tag", "is allowed only for the %s messaging type", MessageTagType)
+	}
+
+	v.messageData("message", m.Message)
+
+	return v.err()
+}
+
+func (v *validator) messageData(field string, d MessageData) {
+	switch {
+	case d.Text == "" && d.Attachment == nil:
+		v.add(field, "either text or attachment is required")
+	case d.Text != "" && d.Attachment != nil:
+		v.add(field, "text and attachment are mutually exclusive")
+	}
+
+	v.maxLength(field+".text", d.Text, MaxTextLength)
+	v.maxLength(field+".metadata", d.Metadata, MaxMetadataLength)
+
+	if d.Attachment != nil {
+		v.attachment(field+".attachment", d.Attachment)
+	}
+
+	if len(d.QuickReplies) > MaxQuickReplies {
+		v.add(field+".quick_replies", "%d quick replies, at most %d allowed", len(d.QuickReplies), MaxQuickReplies)
+	}
+
+	for i, qr := range d.QuickReplies {
+		v.quickReply(fmt.Sprintf("%s.quick_replies[%d]", field, i), qr)
+	}
+}
+
+func (v *validator) quickReply(field string, qr QuickReply) {
+	if qr.ContentType != "" && qr.ContentType != "text" {
+		return
+	}
+
+	v.required(field+".title", qr.Title)
+	v.maxLength(field+".title", qr.Title, MaxQuickReplyTitleLength)
+	v.required(field+".payload", qr.Payload)
+	v.maxLength(field+".payload", qr.Payload, MaxPayloadLength)
+}
+
+func (v *validator) attachment(field string, a *StructuredMessageAttachment) {
+	if a.Template != nil {
+		a.Template.validate(v, field+".payload")
+		return
+	}
+
+	if a.Type != "template" {
+		if a.Payload.Url == "" && a.Payload.AttachmentID == "" {
+			v.add(field+".payload", "either url or attachment_id is required")
+		}
+		return
+	}
+
+	p := a.Payload
+	field += ".payload"
+
+	switch p.TemplateType {
+	case "generic":
+		v.elements(field+".elements", p.Elements, 1, MaxGenericElements, MaxElementButtons)
+	case "list":
+		v.elements(field+".elements", p.Elements, MinListElements, MaxListElements, 1)
+		if p.Buttons != nil && len(*p.Buttons) > 1 {
+			v.add(field+".buttons", "%d buttons, at most 1 allowed", len(*p.Buttons))
+		}
+	case "button":
+		v.required(field+".text", p.Text)
+		v.maxLength(field+".text", p.Text, MaxButtonTemplateTextLength)
+		if p.Buttons == nil || len(*p.Buttons) == 0 {
+			v.add(field+".buttons", "at least 1 button is required")
+		} else if len(*p.Buttons) > MaxTemplateButtons {
+			v.add(field+".buttons", "%d buttons, at most %d allowed", len(*p.Buttons), MaxTemplateButtons)
+		}
+	}
+
+	if p.Buttons != nil {
+		v.buttons(field+".buttons", *p.Buttons)
+	}
+}
+
+func (v *validator) elements(field string, elements *[]StructuredMessageElement, min, max, maxButtons int) {
+	var n int
+	if elements != nil {
+		n = len(*elements)
+	}
+
+	switch {
+	case n < min:
+		v.add(field, "%d elements, at least %d required", n, min)
+	case n > max:
+		v.add(field, "%d elements, at most %d allowed", n, max)
+	}
+
+	for i := 0; i < n; i++ {
+		e := (*elements)[i]
+		ef := fmt.Sprintf("%s[%d]", field, i)
+
+		v.required(ef+".title", e.Title)
+		v.maxLength(ef+".title", e.Title, MaxElementTitleLength)
+		v.maxLength(ef+".subtitle", e.Subtitle, MaxElementTitleLength)
+
+		if e.Buttons == nil {
+			continue
+		}
+
+		if len(*e.Buttons) > maxButtons {
+			v.add(ef+".buttons", "%d buttons, at most %d allowed", len(*e.Buttons), maxButtons)
+		}
+		v.buttons(ef+".buttons", *e.Buttons)
+	}
+}
+
+func (v *validator) buttons(field string, buttons []StructuredMessageButton) {
+	for i, b := range buttons {
+		bf := fmt.Sprintf("%s[%d]", field, i)
+
+		switch b.Type {
+		case "web_url", "account_link":
+			v.required(bf+".url", b.URL)
+		case "postback", "phone_number":
+			v.required(bf+".payload", b.Payload)
+		case "":
+			v.add(bf+".type", "is required")
+		}
+
+		switch b.Type {
+		case "element_share", "account_link", "account_unlink":
+		default:
+			v.required(bf+".title", b.Title)
+		}
+		v.maxLength(bf+".title", b.Title, MaxButtonTitleLength)
+		v.maxLength(bf+".payload", b.Payload, MaxPayloadLength)
+	}
+}